@@ -0,0 +1,21 @@
+import axios from 'axios'
+
+// 刷新令牌请求，多个并发的401只触发一次刷新
+let refreshing = null
+
+// 使用刷新令牌换取新的访问令牌，返回新的访问令牌
+export function refreshAccessToken() {
+  if (!refreshing) {
+    const refreshToken = localStorage.getItem('refreshToken')
+    refreshing = axios.post('/api/token/refresh', { refreshToken })
+      .then(response => {
+        localStorage.setItem('token', response.data.token)
+        localStorage.setItem('refreshToken', response.data.refreshToken)
+        return response.data.token
+      })
+      .finally(() => {
+        refreshing = null
+      })
+  }
+  return refreshing
+}
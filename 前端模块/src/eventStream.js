@@ -0,0 +1,115 @@
+import { refreshAccessToken } from './auth'
+
+// 断线重连的初始间隔和最大间隔（毫秒）
+const RETRY_DELAY = 3000
+const MAX_RETRY_DELAY = 60000
+
+// 订阅服务端推送的任务和文件变更（Server-Sent Events）
+// 使用fetch而不是EventSource，以便在请求头中携带令牌和工作区；断线后带着最后的事件ID重连
+// onEvent(type, data)在收到变更时调用，onReset()在重连后无法补发错过的事件时调用，需要重新加载数据
+export function openEventStream({ onEvent, onReset }) {
+  const stream = { closed: false, close }
+  let controller = null
+  let timer = null
+  let lastEventId = ''
+  let delay = RETRY_DELAY
+
+  function close() {
+    stream.closed = true
+    clearTimeout(timer)
+    if (controller) {
+      controller.abort()
+    }
+  }
+
+  async function connect(refreshed = false) {
+    const token = localStorage.getItem('token')
+    if (!token) {
+      // 已登出，不再重连
+      close()
+      return
+    }
+    const headers = { Accept: 'text/event-stream', Authorization: `Bearer ${token}` }
+    const workspaceId = localStorage.getItem('workspaceId')
+    if (workspaceId) {
+      headers['X-Workspace-ID'] = workspaceId
+    }
+    if (lastEventId) {
+      headers['Last-Event-ID'] = lastEventId
+    }
+
+    controller = new AbortController()
+    try {
+      const response = await fetch('/api/events', { headers, signal: controller.signal })
+      if (response.status === 401 && !refreshed && localStorage.getItem('refreshToken')) {
+        // 访问令牌过期，刷新后立即重连
+        await refreshAccessToken()
+        return connect(true)
+      }
+      if (response.ok) {
+        delay = RETRY_DELAY
+        await read(response.body, !!lastEventId)
+      }
+    } catch (error) {
+      if (stream.closed) return
+      console.error('事件流连接中断:', error)
+    }
+    if (!stream.closed) {
+      timer = setTimeout(connect, delay)
+      delay = Math.min(delay * 2, MAX_RETRY_DELAY)
+    }
+  }
+
+  // 读取事件流直到连接关闭，事件之间以空行分隔
+  async function read(body, resuming) {
+    const reader = body.getReader()
+    const decoder = new TextDecoder()
+    let buffer = ''
+    for (;;) {
+      const { value, done } = await reader.read()
+      if (done) return
+      buffer += decoder.decode(value, { stream: true })
+      let index
+      while ((index = buffer.indexOf('\n\n')) !== -1) {
+        handle(buffer.slice(0, index), resuming)
+        buffer = buffer.slice(index + 2)
+      }
+    }
+  }
+
+  // 解析一个事件，忽略以冒号开头的心跳注释
+  function handle(block, resuming) {
+    let type = 'message'
+    const data = []
+    block.split('\n').forEach(line => {
+      if (!line || line.startsWith(':')) return
+      const sep = line.indexOf(':')
+      const field = sep === -1 ? line : line.slice(0, sep)
+      let value = sep === -1 ? '' : line.slice(sep + 1)
+      if (value.startsWith(' ')) {
+        value = value.slice(1)
+      }
+      if (field === 'id') {
+        lastEventId = value
+      } else if (field === 'event') {
+        type = value
+      } else if (field === 'data') {
+        data.push(value)
+      }
+    })
+    if (!data.length) return
+
+    const payload = JSON.parse(data.join('\n'))
+    if (type === 'ready') {
+      // 首次连接时页面自己加载数据，只有重连后无法续传时需要重新加载
+      if (resuming && !payload.resumed) {
+        onReset()
+      }
+    } else {
+      onEvent(type, payload)
+    }
+  }
+
+  connect()
+  return stream
+}
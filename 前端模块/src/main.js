@@ -5,6 +5,7 @@ import store from './store'
 import ElementUI from 'element-ui'
 import 'element-ui/lib/theme-chalk/index.css'
 import axios from 'axios'
+import { refreshAccessToken } from './auth'
 
 // 使用ElementUI
 Vue.use(ElementUI)
@@ -13,21 +14,49 @@ Vue.use(ElementUI)
 // 使用相对路径，通过vue.config.js中的代理配置转发到后端
 axios.defaults.baseURL = ''
 console.log('前端已连接到后端服务器(通过代理)')
-// 请求拦截器，添加token到请求头
+// 请求拦截器，添加token和当前工作区到请求头
 axios.interceptors.request.use(config => {
   const token = localStorage.getItem('token')
   if (token) {
     config.headers.Authorization = `Bearer ${token}`
   }
+  const workspaceId = localStorage.getItem('workspaceId')
+  if (workspaceId) {
+    config.headers['X-Workspace-ID'] = workspaceId
+  }
   return config
 })
 // 响应拦截器，处理未授权错误
 axios.interceptors.response.use(
   response => response,
-  error => {
+  async error => {
+    const original = error.config
+    // 已不是所选工作区的成员时改用个人工作区重试
+    if (error.response && error.response.status === 403 && error.response.data.error === '不是该工作区的成员' &&
+      original && !original._workspaceReset) {
+      original._workspaceReset = true
+      localStorage.removeItem('workspaceId')
+      store.commit('setWorkspace', null)
+      delete original.headers['X-Workspace-ID']
+      return axios(original)
+    }
     if (error.response && error.response.status === 401) {
+      // 访问令牌过期时先尝试使用刷新令牌换取新令牌
+      const canRefresh = localStorage.getItem('refreshToken') &&
+        original && !original._retried && !original.url.startsWith('/api/token/refresh')
+      if (canRefresh) {
+        original._retried = true
+        try {
+          const token = await refreshAccessToken()
+          original.headers.Authorization = `Bearer ${token}`
+          return axios(original)
+        } catch (e) {
+          // 刷新失败，继续走登出流程
+        }
+      }
       // 清除本地存储的token
       localStorage.removeItem('token')
+      localStorage.removeItem('refreshToken')
       // 跳转到登录页
       router.push('/login')
     }
@@ -3,7 +3,9 @@ import VueRouter from 'vue-router'
 import Login from '../views/Login.vue'
 import Home from '../views/Home.vue'
 import FileManager from '../views/FileManager.vue'
+import Board from '../views/Board.vue'
 import Profile from '../views/Profile.vue'
+import Webhooks from '../views/Webhooks.vue'
 
 Vue.use(VueRouter)
 
@@ -25,6 +27,12 @@ const routes = [
     component: Home,
     meta: { requiresAuth: true }
   },
+  {
+    path: '/board',
+    name: 'Board',
+    component: Board,
+    meta: { requiresAuth: true }
+  },
   {
     path: '/files',
     name: 'FileManager',
@@ -36,6 +44,12 @@ const routes = [
     name: 'Profile',
     component: Profile,
     meta: { requiresAuth: true }
+  },
+  {
+    path: '/webhooks',
+    name: 'Webhooks',
+    component: Webhooks,
+    meta: { requiresAuth: true }
   }
 ]
 
@@ -1,15 +1,32 @@
 import Vue from 'vue'
 import Vuex from 'vuex'
 import axios from 'axios'
+import { openEventStream } from '../eventStream'
 
 Vue.use(Vuex)
 
+// 服务端推送的事件流连接
+let eventStream = null
+
 export default new Vuex.Store({
   state: {
     // 用户信息
     user: null,
     // 任务列表
-    tasks: []
+    tasks: [],
+    // 任务列表范围：created自己创建的、assigned自己负责的、shared共享给自己的
+    taskView: 'created',
+    // 当前用户加入的工作区
+    workspaces: [],
+    // 当前工作区ID，为空时使用个人工作区
+    workspaceId: Number(localStorage.getItem('workspaceId')) || null,
+    // 未读通知数
+    unreadCount: 0,
+    // 收到的推送事件数，页面监听后刷新对应数据
+    streamEvents: {
+      task: 0,
+      file: 0
+    }
   },
   mutations: {
     // 设置用户信息
@@ -20,6 +37,33 @@ export default new Vuex.Store({
     setTasks(state, tasks) {
       state.tasks = tasks
     },
+    // 设置任务列表范围
+    setTaskView(state, view) {
+      state.taskView = view
+    },
+    // 设置工作区列表
+    setWorkspaces(state, workspaces) {
+      state.workspaces = workspaces
+    },
+    // 切换当前工作区
+    setWorkspace(state, id) {
+      state.workspaceId = id
+      if (id) {
+        localStorage.setItem('workspaceId', id)
+      } else {
+        localStorage.removeItem('workspaceId')
+      }
+    },
+    // 记录收到的推送事件，kind为task或file
+    receiveStreamEvent(state, kind) {
+      if (kind in state.streamEvents) {
+        state.streamEvents[kind]++
+      }
+    },
+    // 设置未读通知数
+    setUnreadCount(state, count) {
+      state.unreadCount = count
+    },
     // 添加新任务
     addTask(state, task) {
       state.tasks.push(task)
@@ -58,6 +102,7 @@ export default new Vuex.Store({
         console.log('登录响应:', response.data);
         // 保存token到本地存储
         localStorage.setItem('token', response.data.token);
+        localStorage.setItem('refreshToken', response.data.refreshToken);
         // 保存用户信息到状态
         commit('setUser', response.data.user);
         return response;
@@ -102,15 +147,32 @@ export default new Vuex.Store({
       }
     },
     // 获取任务列表
-    async fetchTasks({ commit }) {
+    // 接口按页返回，这里沿着 nextCursor 取完全部任务
+    async fetchTasks({ commit, state }) {
       try {
-        const response = await axios.get('/api/tasks')
-        commit('setTasks', response.data)
+        const tasks = []
+        let cursor = ''
+        let response
+        do {
+          response = await axios.get('/api/tasks', {
+            params: { view: state.taskView, pageSize: 200, cursor: cursor || undefined }
+          })
+          tasks.push(...response.data.items)
+          cursor = response.data.nextCursor
+        } while (cursor)
+        commit('setTasks', tasks)
         return response
       } catch (error) {
         throw error
       }
     },
+    // 搜索任务，结果按相关度排序，不修改任务列表
+    async searchTasks(_, q) {
+      const response = await axios.get('/api/tasks/search', {
+        params: { q, pageSize: 200 }
+      })
+      return response.data.items
+    },
     // 创建新任务
     async createTask({ commit }, taskData) {
       try {
@@ -122,30 +184,299 @@ export default new Vuex.Store({
       }
     },
     // 更新任务
-    async updateTask({ commit }, { id, taskData }) {
+    // 通过If-Match提交客户端持有的版本号，版本不一致时用返回的最新任务更新本地状态
+    async updateTask({ commit }, { id, version, taskData, force }) {
       try {
-        // 使用POST请求替代PUT，与后端路由保持一致
-        const response = await axios.post(`/api/task/update/${id}`, taskData)
+        // 使用POST请求替代PUT，与后端路由保持一致；force为true时强制完成被阻塞的任务
+        const response = await axios.post(`/api/task/update/${id}`, taskData, {
+          headers: { 'If-Match': `"${version}"` },
+          params: force ? { force: true } : {}
+        })
         commit('updateTask', response.data)
         return response
       } catch (error) {
+        if (error.response && error.response.status === 412) {
+          commit('updateTask', error.response.data.current)
+        }
         throw error
       }
     },
     // 删除任务
-    async deleteTask({ commit }, id) {
+    async deleteTask({ commit }, { id, version }) {
       try {
         // 使用POST请求替代DELETE，与后端路由保持一致
-        await axios.post(`/api/task/delete/${id}`)
+        await axios.post(`/api/task/delete/${id}`, null, {
+          headers: { 'If-Match': `"${version}"` }
+        })
         commit('deleteTask', id)
       } catch (error) {
+        if (error.response && error.response.status === 412) {
+          commit('updateTask', error.response.data.current)
+        }
+        throw error
+      }
+    },
+    // 获取任务变更历史
+    async fetchTaskHistory(_, id) {
+      const response = await axios.get(`/api/task/${id}/history`, { params: { pageSize: 200 } })
+      return response.data.items
+    },
+    // 上传文件作为任务附件
+    async uploadAttachment({ commit }, { id, file }) {
+      const formData = new FormData()
+      formData.append('file', file)
+      const response = await axios.post(`/api/task/${id}/attachments`, formData, {
+        headers: { 'Content-Type': 'multipart/form-data' }
+      })
+      commit('updateTask', response.data)
+      return response.data
+    },
+    // 将已上传的文件添加为任务附件
+    async linkAttachment({ commit }, { id, fileId }) {
+      const response = await axios.post(`/api/task/${id}/attachments/link`, { fileId })
+      commit('updateTask', response.data)
+      return response.data
+    },
+    // 从任务中移除附件
+    async unlinkAttachment({ commit }, { id, fileId }) {
+      const response = await axios.post(`/api/task/${id}/attachments/unlink`, { fileId })
+      commit('updateTask', response.data)
+      return response.data
+    },
+    // 添加任务负责人
+    async addAssignee({ commit }, { id, username }) {
+      const response = await axios.post(`/api/task/${id}/assignees/add`, { username })
+      commit('updateTask', response.data)
+      return response.data
+    },
+    // 移除任务负责人
+    async removeAssignee({ commit }, { id, username }) {
+      const response = await axios.post(`/api/task/${id}/assignees/remove`, { username })
+      commit('updateTask', response.data)
+      return response.data
+    },
+    // 获取任务共享记录
+    async fetchShares(_, taskId) {
+      const response = await axios.get(`/api/task/${taskId}/shares`)
+      return response.data
+    },
+    // 共享任务或修改共享权限
+    async shareTask(_, { id, username, permission }) {
+      const response = await axios.post(`/api/task/${id}/shares/add`, { username, permission })
+      return response.data
+    },
+    // 取消共享
+    async unshareTask(_, { id, username }) {
+      const response = await axios.post(`/api/task/${id}/shares/remove`, { username })
+      return response.data
+    },
+    // 获取任务提醒
+    async fetchReminders(_, taskId) {
+      const response = await axios.get(`/api/task/${taskId}/reminders`)
+      return response.data
+    },
+    // 整体替换任务提醒，offsets为截止日期前的分钟数
+    async setReminders(_, { taskId, offsets }) {
+      const response = await axios.post(`/api/task/${taskId}/reminders`, { offsets })
+      return response.data
+    },
+    // 获取任务评论
+    async fetchComments(_, taskId) {
+      const response = await axios.get(`/api/task/${taskId}/comments`, { params: { pageSize: 200 } })
+      return response.data.items
+    },
+    // 发表评论
+    async createComment(_, { taskId, body }) {
+      const response = await axios.post(`/api/task/comment/${taskId}`, { body })
+      return response.data
+    },
+    // 编辑评论
+    async updateComment(_, { id, body }) {
+      const response = await axios.post(`/api/comment/update/${id}`, { body })
+      return response.data
+    },
+    // 删除评论
+    async deleteComment(_, id) {
+      await axios.post(`/api/comment/delete/${id}`)
+    },
+    // 获取默认工作流
+    async fetchWorkflow() {
+      const response = await axios.get('/api/workflow')
+      return response.data
+    },
+    // 获取看板，每列最多取200个任务
+    async fetchBoard() {
+      const response = await axios.get('/api/board', { params: { pageSize: 200 } })
+      return response.data
+    },
+    // 在看板中移动任务，beforeId为空时放到列末尾
+    // 与更新任务相同，通过If-Match提交客户端持有的版本号
+    async moveTaskStatus({ commit }, { id, version, status, beforeId }) {
+      try {
+        const response = await axios.post(`/api/task/status/${id}`, { status, beforeId }, {
+          headers: { 'If-Match': `"${version}"` }
+        })
+        commit('updateTask', response.data)
+        return response.data
+      } catch (error) {
+        if (error.response && error.response.status === 412) {
+          commit('updateTask', error.response.data.current)
+        }
         throw error
       }
     },
+    // 获取回收站中的任务
+    async fetchTrash() {
+      const response = await axios.get('/api/tasks/trash', { params: { pageSize: 200 } })
+      return response.data.items
+    },
+    // 从回收站恢复任务，恢复后重新获取任务列表以包含一起恢复的子任务
+    async restoreTask({ dispatch }, id) {
+      await axios.post(`/api/task/restore/${id}`)
+      await dispatch('fetchTasks')
+    },
+    // 彻底删除回收站中的任务
+    async purgeTask(_, id) {
+      await axios.post(`/api/task/purge/${id}`)
+    },
+    // 清空回收站
+    async emptyTrash() {
+      await axios.post('/api/tasks/trash/empty')
+    },
+    // 获取工作区列表
+    async fetchWorkspaces({ commit }) {
+      const response = await axios.get('/api/workspaces')
+      commit('setWorkspaces', response.data)
+      return response.data
+    },
+    // 创建工作区
+    async createWorkspace({ dispatch }, name) {
+      const response = await axios.post('/api/workspace', { name })
+      await dispatch('fetchWorkspaces')
+      return response.data
+    },
+    // 邀请成员，invite包含username或email以及role
+    async inviteMember(_, { workspaceId, ...invite }) {
+      const response = await axios.post(`/api/workspace/${workspaceId}/invite`, invite)
+      return response.data
+    },
+    // 获取发给我的邀请
+    async fetchInvitations() {
+      const response = await axios.get('/api/invitations')
+      return response.data
+    },
+    // 接受或拒绝邀请
+    async respondInvitation({ dispatch }, { id, accept }) {
+      await axios.post(accept ? '/api/invitation/accept' : '/api/invitation/decline', { id })
+      if (accept) {
+        await dispatch('fetchWorkspaces')
+      }
+    },
+    // 获取未读通知数
+    async fetchUnreadCount({ commit }) {
+      const response = await axios.get('/api/notifications/unread-count')
+      commit('setUnreadCount', response.data.count)
+      return response.data.count
+    },
+    // 获取通知列表，params可包含unread、page、pageSize
+    async fetchNotifications({ commit }, params) {
+      const response = await axios.get('/api/notifications', { params })
+      commit('setUnreadCount', response.data.unread)
+      return response.data
+    },
+    // 将通知标记为已读或未读
+    async markNotification({ dispatch }, { id, read }) {
+      const response = await axios.post(`/api/notification/${read ? 'read' : 'unread'}/${id}`)
+      await dispatch('fetchUnreadCount')
+      return response.data
+    },
+    // 全部标记为已读
+    async markAllNotificationsRead({ commit }) {
+      await axios.post('/api/notifications/read-all')
+      commit('setUnreadCount', 0)
+    },
+    // 获取日历订阅状态
+    async fetchCalendarFeed() {
+      const response = await axios.get('/api/user/calendar')
+      return response.data
+    },
+    // 生成新的日历订阅地址，旧地址失效，返回的path只出现这一次
+    async regenerateCalendarFeed() {
+      const response = await axios.post('/api/user/calendar/regenerate')
+      return response.data
+    },
+    // 关闭日历订阅
+    async disableCalendarFeed() {
+      await axios.post('/api/user/calendar/disable')
+    },
+    // 获取当前工作区的Webhook
+    async fetchWebhooks() {
+      const response = await axios.get('/api/webhooks')
+      return response.data.webhooks
+    },
+    // 创建Webhook，返回的secret只出现这一次
+    async createWebhook(_, webhook) {
+      const response = await axios.post('/api/webhook', webhook)
+      return response.data
+    },
+    // 更新Webhook，rotateSecret为true时返回新的secret
+    async updateWebhook(_, { id, ...data }) {
+      const response = await axios.post(`/api/webhook/update/${id}`, data)
+      return response.data
+    },
+    // 删除Webhook
+    async deleteWebhook(_, id) {
+      await axios.post(`/api/webhook/delete/${id}`)
+    },
+    // 发送测试事件，返回这次投递的结果
+    async testWebhook(_, id) {
+      const response = await axios.post(`/api/webhook/test/${id}`)
+      return response.data
+    },
+    // 获取Webhook的投递日志
+    async fetchWebhookDeliveries(_, { id, params }) {
+      const response = await axios.get(`/api/webhook/${id}/deliveries`, { params })
+      return response.data
+    },
+    // 连接服务端推送，已连接到当前工作区时不重复连接，切换工作区后重新连接
+    startEventStream({ commit, state }) {
+      if (eventStream && !eventStream.closed && eventStream.workspaceId === state.workspaceId) {
+        return
+      }
+      if (eventStream) {
+        eventStream.close()
+      }
+      eventStream = openEventStream({
+        // 事件类型如task.updated、file.uploaded
+        onEvent: type => commit('receiveStreamEvent', type.split('.')[0]),
+        onReset: () => {
+          commit('receiveStreamEvent', 'task')
+          commit('receiveStreamEvent', 'file')
+        }
+      })
+      eventStream.workspaceId = state.workspaceId
+    },
+    // 断开服务端推送
+    stopEventStream() {
+      if (eventStream) {
+        eventStream.close()
+        eventStream = null
+      }
+    },
     // 登出
-    logout({ commit }) {
+    async logout({ commit, dispatch }) {
+      // 通知后端吊销令牌，失败不影响本地登出
+      try {
+        await axios.post('/api/logout', { refreshToken: localStorage.getItem('refreshToken') })
+      } catch (error) {
+        console.error('登出请求失败:', error.message)
+      }
+      dispatch('stopEventStream')
       // 清除本地存储的token
       localStorage.removeItem('token')
+      localStorage.removeItem('refreshToken')
+      commit('setWorkspace', null)
+      commit('setUnreadCount', 0)
       // 清除用户信息
       commit('setUser', null)
       // 清除任务列表
@@ -160,6 +491,9 @@ export default new Vuex.Store({
     // 获取已完成任务
     getCompletedTasks: state => state.tasks.filter(task => task.completed),
     // 获取未完成任务
-    getIncompleteTasks: state => state.tasks.filter(task => !task.completed)
+    getIncompleteTasks: state => state.tasks.filter(task => !task.completed),
+    // 获取当前工作区，未选择时为个人工作区
+    getWorkspace: state => state.workspaces.find(ws => ws.id === state.workspaceId) ||
+      state.workspaces.find(ws => ws.personal) || null
   }
 })
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // 数据库配置
@@ -18,11 +22,58 @@ type ServerConfig struct {
 	Port string
 }
 
+// JWT配置
+type JWTConfig struct {
+	Algorithm      string        // 签名算法：HS256、RS256或EdDSA
+	KeyID          string        // 当前签名密钥的kid
+	Secret         string        // HS256共享密钥
+	PrivateKeyFile string        // RS256/EdDSA私钥PEM文件路径
+	VerifyKeys     []string      // 额外的验证密钥，格式为 kid:算法:密钥或公钥PEM文件路径
+	AccessTTL      time.Duration // 访问令牌有效期
+	RefreshTTL     time.Duration // 刷新令牌有效期
+}
+
+// 回收站配置
+type TrashConfig struct {
+	RetentionDays int           // 回收站中的任务保留天数，0表示不自动清理
+	PurgeInterval time.Duration // 后台清理的执行间隔
+}
+
+// 到期提醒配置
+type ReminderConfig struct {
+	Interval     time.Duration // 后台检查到期提醒的间隔
+	MaxAttempts  int           // 每个渠道的最大尝试次数，超过后放弃投递
+	SMTPAddr     string        // SMTP服务器地址，为空时不发送邮件
+	SMTPFrom     string        // 发件人地址
+	SMTPUsername string        // SMTP用户名，为空时不认证
+	SMTPPassword string
+	WebhookURL   string // 接收提醒的Webhook地址，为空时不发送
+}
+
+// 实时推送配置
+type StreamConfig struct {
+	Heartbeat time.Duration // 空闲时发送心跳的间隔，防止代理断开连接
+	Backlog   int           // 保留的最近事件数，用于断线续传
+}
+
+// 外发Webhook配置
+type WebhookConfig struct {
+	Interval     time.Duration // 没有新事件时检查待重试投递的最长间隔
+	Timeout      time.Duration // 每次请求的超时时间
+	MaxAttempts  int           // 最大尝试次数，超过后放弃投递
+	RetryBackoff time.Duration // 第一次重试的等待时间，之后每次翻倍
+	AllowPrivate bool          // 允许向本机和内网地址发送，仅用于开发和测试
+}
+
 // 应用配置
 type Config struct {
 	DB                 DbConfig
 	Server             ServerConfig
-	JWTKey             string
+	JWT                JWTConfig
+	Trash              TrashConfig
+	Reminder           ReminderConfig
+	Stream             StreamConfig
+	Webhook            WebhookConfig
 	CORSAllowedOrigins []string
 }
 
@@ -36,7 +87,29 @@ func GetConfig() Config {
 	dbName := getEnv("DB_NAME", "task_manager")
 
 	serverPort := getEnv("SERVER_PORT", "8080")
+	jwtAlg := getEnv("JWT_ALG", "HS256")
+	jwtKeyID := getEnv("JWT_KID", "default")
 	jwtKey := getEnv("JWT_KEY", "your_secret_key_for_jwt_please_change_in_production")
+	jwtPrivateKeyFile := getEnv("JWT_PRIVATE_KEY_FILE", "")
+	jwtVerifyKeys := getListEnv("JWT_VERIFY_KEYS")
+	accessTTL := getDurationEnv("JWT_ACCESS_TTL", 15*time.Minute)
+	refreshTTL := getDurationEnv("JWT_REFRESH_TTL", 7*24*time.Hour)
+	trashRetentionDays := getIntEnv("TRASH_RETENTION_DAYS", 30)
+	trashPurgeInterval := getDurationEnv("TRASH_PURGE_INTERVAL", time.Hour)
+	reminderInterval := getDurationEnv("REMINDER_INTERVAL", time.Minute)
+	reminderMaxAttempts := getIntEnv("REMINDER_MAX_ATTEMPTS", 5)
+	smtpAddr := getEnv("SMTP_ADDR", "")
+	smtpFrom := getEnv("SMTP_FROM", "taskmanager@localhost")
+	smtpUsername := getEnv("SMTP_USERNAME", "")
+	smtpPassword := getEnv("SMTP_PASSWORD", "")
+	reminderWebhookURL := getEnv("REMINDER_WEBHOOK_URL", "")
+	streamHeartbeat := getDurationEnv("STREAM_HEARTBEAT", 25*time.Second)
+	streamBacklog := getIntEnv("STREAM_BACKLOG", 1000)
+	webhookInterval := getDurationEnv("WEBHOOK_INTERVAL", 30*time.Second)
+	webhookTimeout := getDurationEnv("WEBHOOK_TIMEOUT", 10*time.Second)
+	webhookMaxAttempts := getIntEnv("WEBHOOK_MAX_ATTEMPTS", 8)
+	webhookRetryBackoff := getDurationEnv("WEBHOOK_RETRY_BACKOFF", 30*time.Second)
+	webhookAllowPrivate := getBoolEnv("WEBHOOK_ALLOW_PRIVATE", false)
 
 	// 允许的跨域来源
 	corsOrigins := []string{"http://localhost:8081"}
@@ -52,7 +125,39 @@ func GetConfig() Config {
 		Server: ServerConfig{
 			Port: serverPort,
 		},
-		JWTKey:             jwtKey,
+		JWT: JWTConfig{
+			Algorithm:      jwtAlg,
+			KeyID:          jwtKeyID,
+			Secret:         jwtKey,
+			PrivateKeyFile: jwtPrivateKeyFile,
+			VerifyKeys:     jwtVerifyKeys,
+			AccessTTL:      accessTTL,
+			RefreshTTL:     refreshTTL,
+		},
+		Trash: TrashConfig{
+			RetentionDays: trashRetentionDays,
+			PurgeInterval: trashPurgeInterval,
+		},
+		Reminder: ReminderConfig{
+			Interval:     reminderInterval,
+			MaxAttempts:  reminderMaxAttempts,
+			SMTPAddr:     smtpAddr,
+			SMTPFrom:     smtpFrom,
+			SMTPUsername: smtpUsername,
+			SMTPPassword: smtpPassword,
+			WebhookURL:   reminderWebhookURL,
+		},
+		Stream: StreamConfig{
+			Heartbeat: streamHeartbeat,
+			Backlog:   streamBacklog,
+		},
+		Webhook: WebhookConfig{
+			Interval:     webhookInterval,
+			Timeout:      webhookTimeout,
+			MaxAttempts:  webhookMaxAttempts,
+			RetryBackoff: webhookRetryBackoff,
+			AllowPrivate: webhookAllowPrivate,
+		},
 		CORSAllowedOrigins: corsOrigins,
 	}
 }
@@ -66,6 +171,59 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// 从环境变量获取逗号分隔的列表，忽略空项
+func getListEnv(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// 从环境变量获取时长（如 15m、168h），解析失败时使用默认值
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("环境变量 %s 的值无效: %s，使用默认值 %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+// 从环境变量获取非负整数，解析失败时使用默认值
+func getIntEnv(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("环境变量 %s 的值无效: %s，使用默认值 %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// 从环境变量获取布尔值（true、false、1、0等），解析失败时使用默认值
+func getBoolEnv(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("环境变量 %s 的值无效: %s，使用默认值 %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
 // GetDSN 获取数据库连接字符串
 func (c *Config) GetDSN() string {
 	return c.DB.User + ":" + c.DB.Password + "@(" + c.DB.Host + ":" + c.DB.Port + ")/" + c.DB.DbName + "?charset=utf8mb4&parseTime=True&loc=Local"
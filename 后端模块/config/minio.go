@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 	"os"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -14,11 +15,12 @@ var MinioClient *minio.Client
 
 // MinioConfig MinIO配置
 type MinioConfig struct {
-	Endpoint  string
-	AccessKey string
-	SecretKey string
-	UseSSL    bool
-	Bucket    string
+	Endpoint   string
+	AccessKey  string
+	SecretKey  string
+	UseSSL     bool
+	Bucket     string
+	PresignTTL time.Duration // 预签名下载链接有效期
 }
 
 // GetMinioConfig 获取MinIO配置
@@ -45,11 +47,12 @@ func GetMinioConfig() MinioConfig {
 	}
 
 	return MinioConfig{
-		Endpoint:  endpoint,
-		AccessKey: accessKey,
-		SecretKey: secretKey,
-		UseSSL:    false,
-		Bucket:    bucket,
+		Endpoint:   endpoint,
+		AccessKey:  accessKey,
+		SecretKey:  secretKey,
+		UseSSL:     false,
+		Bucket:     bucket,
+		PresignTTL: getDurationEnv("MINIO_PRESIGN_TTL", 15*time.Minute),
 	}
 }
 
@@ -78,24 +81,12 @@ func InitMinio() {
 			log.Fatalf("创建存储桶失败: %v", err)
 		}
 		log.Printf("成功创建存储桶: %s", config.Bucket)
+	}
 
-		// 设置存储桶策略，允许公共读取
-		policy := `{
-			"Version": "2012-10-17",
-			"Statement": [
-				{
-					"Effect": "Allow",
-					"Principal": {"AWS": ["*"]},
-					"Action": ["s3:GetObject"],
-					"Resource": ["arn:aws:s3:::` + config.Bucket + `/*"]
-				}
-			]
-		}`
-
-		err = minioClient.SetBucketPolicy(context.Background(), config.Bucket, policy)
-		if err != nil {
-			log.Fatalf("设置存储桶策略失败: %v", err)
-		}
+	// 存储桶保持私有，清除可能残留的公共读取策略，所有访问都通过预签名链接
+	err = minioClient.SetBucketPolicy(context.Background(), config.Bucket, "")
+	if err != nil {
+		log.Fatalf("清除存储桶策略失败: %v", err)
 	}
 
 	MinioClient = minioClient
@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/models"
+)
+
+// AttachmentRequest 关联或移除已有文件的请求结构
+type AttachmentRequest struct {
+	FileID uint `json:"fileId" binding:"required"` // 文件ID
+}
+
+// GetTaskAttachments 获取任务的附件列表
+func GetTaskAttachments(c *gin.Context) {
+	task, ok := findTask(c, accessView)
+	if !ok {
+		return
+	}
+
+	var files []models.File
+	if err := db.Model(&task).Order("id ASC").Association("Attachments").Find(&files).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取附件失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attachments": toFileResponses(files)})
+}
+
+// UploadTaskAttachment 上传文件并作为附件添加到任务
+// 直接上传到任务的文件在任务被彻底删除时一并删除
+func UploadTaskAttachment(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	task, ok := findTask(c, accessEdit)
+	if !ok {
+		return
+	}
+
+	fileRecord, ok := receiveUpload(c, userID.(uint))
+	if !ok {
+		return
+	}
+	fileRecord.TaskID = &task.ID
+
+	tx := db.Begin()
+	err := changeAttachments(tx, &task, userID.(uint), func(tx *gorm.DB) error {
+		if err := tx.Create(&fileRecord).Error; err != nil {
+			return err
+		}
+		return tx.Model(&task).Association("Attachments").Append(&fileRecord).Error
+	})
+	if err == nil {
+		err = tx.Commit().Error
+	} else {
+		tx.Rollback()
+	}
+	if err != nil {
+		// 元数据写入失败时清理已上传的对象，避免产生孤儿对象
+		removeObject(fileRecord.ObjectKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加附件失败"})
+		return
+	}
+	publishFileEvent(streamFileUploaded, fileRecord)
+	publishTaskEvent(streamTaskUpdated, task)
+
+	respondTask(c, task)
+}
+
+// LinkTaskAttachment 将已上传的文件作为附件添加到任务
+func LinkTaskAttachment(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	task, ok := findTask(c, accessEdit)
+	if !ok {
+		return
+	}
+
+	var attachReq AttachmentRequest
+	if err := c.ShouldBindJSON(&attachReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	// 确保用户只能添加自己在任务所属工作区的文件
+	var file models.File
+	if db.Where("id = ? AND user_id = ? AND workspace_id = ?", attachReq.FileID, userID, task.WorkspaceID).First(&file).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在或无权限"})
+		return
+	}
+	if isAttached(task.ID, file.ID) {
+		respondTask(c, task)
+		return
+	}
+
+	tx := db.Begin()
+	if err := changeAttachments(tx, &task, userID.(uint), func(tx *gorm.DB) error {
+		return tx.Model(&task).Association("Attachments").Append(&file).Error
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加附件失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加附件失败"})
+		return
+	}
+	publishTaskEvent(streamTaskUpdated, task)
+
+	respondTask(c, task)
+}
+
+// UnlinkTaskAttachment 从任务中移除附件，文件本身保留在文件列表中
+func UnlinkTaskAttachment(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	task, ok := findTask(c, accessEdit)
+	if !ok {
+		return
+	}
+
+	var attachReq AttachmentRequest
+	if err := c.ShouldBindJSON(&attachReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if !isAttached(task.ID, attachReq.FileID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务中不存在该附件"})
+		return
+	}
+
+	tx := db.Begin()
+	if err := changeAttachments(tx, &task, userID.(uint), func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM task_attachments WHERE task_id = ? AND file_id = ?", task.ID, attachReq.FileID).Error; err != nil {
+			return err
+		}
+		// 移除后不再随该任务删除
+		return tx.Model(&models.File{}).Where("id = ? AND task_id = ?", attachReq.FileID, task.ID).
+			UpdateColumn("task_id", nil).Error
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除附件失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除附件失败"})
+		return
+	}
+	publishTaskEvent(streamTaskUpdated, task)
+
+	respondTask(c, task)
+}
+
+// isAttached 判断文件是否已是任务的附件
+func isAttached(taskID, fileID uint) bool {
+	var count int
+	db.Table("task_attachments").Where("task_id = ? AND file_id = ?", taskID, fileID).Count(&count)
+	return count > 0
+}
+
+// changeAttachments 在事务中修改任务的附件，更新任务版本号并写入变更记录
+func changeAttachments(tx *gorm.DB, task *models.Task, actorID uint, change func(tx *gorm.DB) error) error {
+	before, err := attachmentNames(tx, task.ID)
+	if err != nil {
+		return err
+	}
+	if err := change(tx); err != nil {
+		return err
+	}
+	after, err := attachmentNames(tx, task.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(task).Updates(map[string]interface{}{"version": bumpVersion}).Error; err != nil {
+		return err
+	}
+	task.Version++
+	changes := []models.FieldChange{{Field: "attachments", Old: before, New: after}}
+	return recordTaskEvent(tx, task.ID, actorID, models.TaskEventUpdated, changes)
+}
+
+// detachFile 从引用文件的全部任务中移除该文件，在删除文件时调用，返回受影响的任务ID
+func detachFile(tx *gorm.DB, file models.File) ([]uint, error) {
+	var taskIDs []uint
+	if err := tx.Table("task_attachments").Where("file_id = ?", file.ID).Pluck("task_id", &taskIDs).Error; err != nil {
+		return nil, err
+	}
+
+	for _, taskID := range taskIDs {
+		task := models.Task{}
+		task.ID = taskID
+		if err := changeAttachments(tx, &task, file.UserID, func(tx *gorm.DB) error {
+			return tx.Exec("DELETE FROM task_attachments WHERE task_id = ? AND file_id = ?", taskID, file.ID).Error
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return taskIDs, nil
+}
+
+// attachmentNames 返回任务附件的文件名，按文件ID排序
+func attachmentNames(tx *gorm.DB, taskID uint) ([]string, error) {
+	names := []string{}
+	err := tx.Table("files").
+		Joins("JOIN task_attachments ON task_attachments.file_id = files.id").
+		Where("task_attachments.task_id = ? AND files.deleted_at IS NULL", taskID).
+		Order("files.id ASC").
+		Pluck("files.original_name", &names).Error
+	return names, err
+}
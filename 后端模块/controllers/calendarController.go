@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/ical"
+	"taskmanager/models"
+	"taskmanager/recurrence"
+)
+
+// 已完成的任务只导出截止日期在最近多少天内的
+const calendarCompletedDays = 90
+
+// 每次最多导出的任务数，按截止日期从晚到早选取
+const calendarMaxTasks = 2000
+
+// 客户端重新读取日历的建议间隔
+const calendarRefreshInterval = "PT1H"
+
+// 导出的任务优先级，RFC 5545中1最高、9最低
+var calendarPriorities = map[models.Priority]string{
+	models.High:   "1",
+	models.Medium: "5",
+	models.Low:    "9",
+}
+
+// GetCalendarFeedInfo 获取当前用户的日历订阅状态
+func GetCalendarFeedInfo(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var feed models.CalendarFeed
+	if db.Where("user_id = ?", userID).First(&feed).RecordNotFound() {
+		c.JSON(http.StatusOK, models.CalendarFeedResponse{})
+		return
+	}
+	c.JSON(http.StatusOK, toCalendarFeedResponse(feed, ""))
+}
+
+// RegenerateCalendarFeed 生成新的日历订阅令牌，旧的订阅地址立即失效
+// 订阅地址只在响应中返回这一次
+func RegenerateCalendarFeed(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 与刷新令牌相同，只保存令牌摘要
+	raw, err := generateRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成订阅地址失败"})
+		return
+	}
+
+	feed := models.CalendarFeed{UserID: userID.(uint), TokenHash: hashToken(raw)}
+	tx := db.Begin()
+	if err := tx.Where("user_id = ?", feed.UserID).Delete(&models.CalendarFeed{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成订阅地址失败"})
+		return
+	}
+	if err := tx.Create(&feed).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成订阅地址失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成订阅地址失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toCalendarFeedResponse(feed, "/api/calendar/"+raw+".ics"))
+}
+
+// DisableCalendarFeed 关闭日历订阅，订阅地址立即失效
+func DisableCalendarFeed(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	if err := db.Where("user_id = ?", userID).Delete(&models.CalendarFeed{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "关闭日历订阅失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "日历订阅已关闭"})
+}
+
+// GetCalendarFeed 以iCalendar格式返回用户有截止日期的任务，通过地址中的令牌认证，不需要登录
+// 包括用户所在工作区中自己创建、共享给自己和自己负责的任务；type=todo时导出为VTODO，默认为VEVENT；
+// workspace参数可以只导出一个工作区的任务
+func GetCalendarFeed(c *gin.Context) {
+	raw := strings.TrimSuffix(c.Param("token"), ".ics")
+	var feed models.CalendarFeed
+	if raw == "" || db.Where("token_hash = ?", hashToken(raw)).First(&feed).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅地址无效或已失效"})
+		return
+	}
+	var user models.User
+	if err := db.First(&user, feed.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅地址无效或已失效"})
+		return
+	}
+
+	component := "VEVENT"
+	switch c.DefaultQuery("type", "event") {
+	case "event":
+	case "todo":
+		component = "VTODO"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的type，可选值为: event, todo"})
+		return
+	}
+
+	workspaces := db.Model(&models.Membership{}).Select("workspace_id").Where("user_id = ?", user.ID)
+	if v := c.Query("workspace"); v != "" {
+		workspaceID, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的工作区ID"})
+			return
+		}
+		workspaces = workspaces.Where("workspace_id = ?", workspaceID)
+	}
+
+	now := time.Now()
+	var tasks []models.Task
+	if err := db.Preload("Tags").
+		Where("workspace_id IN ?", workspaces.SubQuery()).
+		Where("user_id = ? OR id IN ? OR id IN ?", user.ID, accessibleTaskIDs(user.ID, "shared"), accessibleTaskIDs(user.ID, "assigned")).
+		Where("due_date IS NOT NULL").
+		Where("completed = ? OR due_date >= ?", false, now.AddDate(0, 0, -calendarCompletedDays)).
+		Order("due_date DESC").Order("id DESC").
+		Limit(calendarMaxTasks).
+		Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成日历失败"})
+		return
+	}
+
+	if err := db.Model(&feed).UpdateColumn("last_fetched_at", now).Error; err != nil {
+		log.Printf("记录日历订阅 %d 的读取时间失败: %v", feed.ID, err)
+	}
+
+	var cal ical.Calendar
+	cal.Begin("VCALENDAR")
+	cal.Prop("VERSION", "2.0")
+	cal.Prop("PRODID", "-//TaskManager//Tasks//ZH")
+	cal.Prop("CALSCALE", "GREGORIAN")
+	cal.Prop("METHOD", "PUBLISH")
+	cal.Text("X-WR-CALNAME", user.Username+"的任务")
+	cal.Prop("REFRESH-INTERVAL;VALUE=DURATION", calendarRefreshInterval)
+	cal.Prop("X-PUBLISHED-TTL", calendarRefreshInterval)
+	// 重复任务的时间使用截止时间所在的时区，写入用到的时区定义
+	zones := make(map[string]bool)
+	for _, task := range tasks {
+		if loc := task.DueDate.Location(); calendarRRule(task) != "" && !zones[ical.TZID(loc)] {
+			zones[ical.TZID(loc)] = true
+			cal.Timezone(loc, now.Year())
+		}
+	}
+	for _, task := range tasks {
+		writeCalendarTask(&cal, component, task, now)
+	}
+	cal.End("VCALENDAR")
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("Content-Disposition", `inline; filename="tasks.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", cal.Bytes())
+}
+
+// writeCalendarTask 将任务写为一个VEVENT或VTODO
+// 截止时间作为VEVENT的开始时间（不占用时长）或VTODO的DUE；只有最新一次未完成的重复任务带有RRULE，
+// 之前的各次已作为单独的任务导出。带RRULE的任务按截止时间所在时区的本地时间导出，
+// 与服务端计算下一次时使用的时区一致，避免BYDAY、BYMONTHDAY在其他时区展开到另一天
+func writeCalendarTask(cal *ical.Calendar, component string, task models.Task, now time.Time) {
+	rrule := calendarRRule(task)
+	writeDue := func(name string) {
+		if rrule != "" {
+			cal.LocalTime(name, *task.DueDate, task.DueDate.Location())
+		} else {
+			cal.Time(name, *task.DueDate)
+		}
+	}
+
+	cal.Begin(component)
+	cal.Prop("UID", fmt.Sprintf("task-%d@taskmanager", task.ID))
+	cal.Time("DTSTAMP", now)
+	cal.Time("CREATED", task.CreatedAt)
+	cal.Time("LAST-MODIFIED", task.UpdatedAt)
+	cal.Prop("SEQUENCE", strconv.Itoa(task.Version))
+
+	summary := task.Title
+	if component == "VEVENT" {
+		writeDue("DTSTART")
+		// 截止时间不占用日程，不影响忙闲状态
+		cal.Prop("TRANSP", "TRANSPARENT")
+		if task.Completed {
+			summary = "[已完成] " + summary
+		}
+	} else {
+		writeDue("DUE")
+		if task.Completed {
+			cal.Prop("STATUS", "COMPLETED")
+			cal.Prop("PERCENT-COMPLETE", "100")
+		} else {
+			cal.Prop("STATUS", "NEEDS-ACTION")
+		}
+	}
+	cal.Text("SUMMARY", summary)
+	if task.Description != "" {
+		cal.Text("DESCRIPTION", task.Description)
+	}
+	if priority, ok := calendarPriorities[task.Priority]; ok {
+		cal.Prop("PRIORITY", priority)
+	}
+	if len(task.Tags) > 0 {
+		names := make([]string, len(task.Tags))
+		for i, tag := range task.Tags {
+			names[i] = ical.Escape(tag.Name)
+		}
+		cal.Prop("CATEGORIES", strings.Join(names, ","))
+	}
+
+	if rrule != "" {
+		cal.Prop("RRULE", rrule)
+	}
+	cal.End(component)
+}
+
+// calendarRRule 返回任务导出时的RRULE，只有最新一次未完成的重复任务才有，其他任务为空
+func calendarRRule(task models.Task) string {
+	if task.Recurrence == "" || task.Completed || task.NextOccurrenceID != nil {
+		return ""
+	}
+	rule, err := recurrence.Parse(task.Recurrence)
+	if err != nil {
+		return ""
+	}
+	rrule, _ := rule.ICal(task.Occurrence, task.DueDate.Location())
+	return rrule
+}
+
+// toCalendarFeedResponse 转换为响应模型，path只在生成令牌时传入
+func toCalendarFeedResponse(feed models.CalendarFeed, path string) models.CalendarFeedResponse {
+	createdAt := feed.CreatedAt
+	return models.CalendarFeedResponse{
+		Enabled:       true,
+		Path:          path,
+		CreatedAt:     &createdAt,
+		LastFetchedAt: feed.LastFetchedAt,
+	}
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"taskmanager/ical"
+	"taskmanager/models"
+)
+
+func TestWriteCalendarTaskMonthEnd(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("没有时区数据: %v", err)
+	}
+	now := time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		due        time.Time
+		recurrence string
+		occurrence int
+		want       []string
+		notWant    []string
+	}{
+		{
+			"31日的每月任务在短月份取最后一天",
+			time.Date(2026, 1, 31, 9, 0, 0, 0, loc), "FREQ=MONTHLY;BYMONTHDAY=31", 1,
+			[]string{"DUE;TZID=Asia/Shanghai:20260131T090000", "RRULE:FREQ=MONTHLY;BYMONTHDAY=28,29,30,31;BYSETPOS=-1"},
+			[]string{"BYMONTHDAY=31\r\n"},
+		},
+		{
+			"落在2月28日后仍按31日重复",
+			time.Date(2026, 2, 28, 9, 0, 0, 0, loc), "FREQ=MONTHLY;BYMONTHDAY=31;COUNT=6", 2,
+			[]string{"DUE;TZID=Asia/Shanghai:20260228T090000", "RRULE:FREQ=MONTHLY;BYMONTHDAY=28,29,30,31;BYSETPOS=-1;COUNT=5"},
+			nil,
+		},
+		{
+			"不重复的任务使用UTC时间",
+			time.Date(2026, 1, 31, 9, 0, 0, 0, loc), "", 1,
+			[]string{"DUE:20260131T010000Z"},
+			[]string{"RRULE"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due := tt.due
+			task := models.Task{Title: "月底对账", DueDate: &due, Recurrence: tt.recurrence, Occurrence: tt.occurrence, Version: 1}
+			task.ID = 7
+
+			var cal ical.Calendar
+			writeCalendarTask(&cal, "VTODO", task, now)
+			got := string(cal.Bytes())
+			for _, line := range tt.want {
+				if !strings.Contains(got, line+"\r\n") {
+					t.Errorf("缺少 %q:\n%s", line, got)
+				}
+			}
+			for _, line := range tt.notWant {
+				if strings.Contains(got, line) {
+					t.Errorf("不应包含 %q:\n%s", line, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/markdown"
+	"taskmanager/models"
+)
+
+// 评论正文的最大长度（字符数）
+const maxCommentLength = 10000
+
+// CommentRequest 创建和编辑评论的请求结构
+type CommentRequest struct {
+	Body string `json:"body"` // Markdown正文，必填
+}
+
+// GetComments 获取任务的评论，按时间正序
+func GetComments(c *gin.Context) {
+	task, ok := findTask(c, accessView)
+	if !ok {
+		return
+	}
+
+	// 解析分页参数
+	pageSize, err := parsePageSize(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := parsePageNumber(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := db.Model(&models.Comment{}).Where("task_id = ?", task.ID)
+	var total int
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取评论失败"})
+		return
+	}
+
+	var comments []models.Comment
+	if err := query.Order("created_at ASC").Order("id ASC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取评论失败"})
+		return
+	}
+
+	items, err := buildCommentResponses(comments)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取评论失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CommentListResponse{
+		Items:    items,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// CreateComment 在任务下发表评论，记录正文中@提及的用户并发送通知
+func CreateComment(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	task, ok := findTask(c, accessView)
+	if !ok {
+		return
+	}
+
+	body, ok := bindCommentBody(c)
+	if !ok {
+		return
+	}
+
+	comment := models.Comment{
+		TaskID: task.ID,
+		UserID: userID.(uint),
+		Body:   body,
+	}
+
+	tx := db.Begin()
+	if err := tx.Create(&comment).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发表评论失败"})
+		return
+	}
+	mentioned, err := syncCommentMentions(tx, task, comment)
+	if err == nil {
+		err = notifyComment(tx, task, comment, mentioned, true)
+	}
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发表评论失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发表评论失败"})
+		return
+	}
+
+	respondComment(c, comment)
+}
+
+// UpdateComment 编辑评论，只有作者可以编辑
+func UpdateComment(c *gin.Context) {
+	comment, ok := findOwnComment(c)
+	if !ok {
+		return
+	}
+
+	body, ok := bindCommentBody(c)
+	if !ok {
+		return
+	}
+	if body == comment.Body {
+		respondComment(c, comment)
+		return
+	}
+
+	now := time.Now()
+	tx := db.Begin()
+	if err := tx.Model(&comment).Updates(map[string]interface{}{
+		"body":      body,
+		"edited_at": now,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "编辑评论失败"})
+		return
+	}
+	comment.Body = body
+	comment.EditedAt = &now
+	var task models.Task
+	err := tx.First(&task, comment.TaskID).Error
+	var mentioned []uint
+	if err == nil {
+		mentioned, err = syncCommentMentions(tx, task, comment)
+	}
+	if err == nil && len(mentioned) > 0 {
+		err = notifyComment(tx, task, comment, mentioned, false)
+	}
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "编辑评论失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "编辑评论失败"})
+		return
+	}
+
+	respondComment(c, comment)
+}
+
+// DeleteComment 删除评论，只有作者可以删除
+func DeleteComment(c *gin.Context) {
+	comment, ok := findOwnComment(c)
+	if !ok {
+		return
+	}
+
+	tx := db.Begin()
+	if err := tx.Delete(&comment).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除评论失败"})
+		return
+	}
+	if err := tx.Where("comment_id = ?", comment.ID).Delete(&models.CommentMention{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除评论失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除评论失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "评论已删除"})
+}
+
+// bindCommentBody 绑定并校验评论正文，失败时已写入错误响应
+func bindCommentBody(c *gin.Context) (string, bool) {
+	var commentReq CommentRequest
+	if err := c.ShouldBindJSON(&commentReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的评论数据"})
+		return "", false
+	}
+
+	body := strings.TrimSpace(commentReq.Body)
+	if body == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "评论内容不能为空"})
+		return "", false
+	}
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "评论内容不能超过" + strconv.Itoa(maxCommentLength) + "个字符"})
+		return "", false
+	}
+	return body, true
+}
+
+// findOwnComment 根据URL参数查找当前用户发表的评论，失败时已写入错误响应
+// 评论所在任务已删除时视为不存在
+func findOwnComment(c *gin.Context) (models.Comment, bool) {
+	var comment models.Comment
+
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return comment, false
+	}
+
+	// 获取评论ID
+	commentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的评论ID"})
+		return comment, false
+	}
+
+	if db.Where("id = ?", commentID).First(&comment).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "评论不存在"})
+		return comment, false
+	}
+	if !inWorkspace(comment.TaskID, currentWorkspace(c)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "评论不存在"})
+		return comment, false
+	}
+	if comment.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能修改自己发表的评论"})
+		return comment, false
+	}
+	return comment, true
+}
+
+// syncCommentMentions 根据评论正文更新提及记录，返回新提及的用户ID
+// 只记录可以查看该任务的用户，其他@用户名按普通文本显示；编辑后仍被提及的用户保留原记录，避免重复通知
+func syncCommentMentions(tx *gorm.DB, task models.Task, comment models.Comment) ([]uint, error) {
+	var users []models.User
+	if names := markdown.Mentions(comment.Body); len(names) > 0 {
+		var candidates []models.User
+		if err := tx.Select("id").Where("username IN (?) AND id <> ?", names, comment.UserID).
+			Find(&candidates).Error; err != nil {
+			return nil, err
+		}
+		for _, user := range candidates {
+			levels, err := taskAccessLevels(user.ID, []models.Task{task})
+			if err != nil {
+				return nil, err
+			}
+			if levels[task.ID] != accessNone {
+				users = append(users, user)
+			}
+		}
+	}
+
+	var existing []models.CommentMention
+	if err := tx.Where("comment_id = ?", comment.ID).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	mentioned := make(map[uint]bool, len(existing))
+	for _, mention := range existing {
+		mentioned[mention.UserID] = true
+	}
+
+	keep := make(map[uint]bool, len(users))
+	var added []uint
+	for _, user := range users {
+		keep[user.ID] = true
+		if mentioned[user.ID] {
+			continue
+		}
+		if err := tx.Create(&models.CommentMention{CommentID: comment.ID, UserID: user.ID}).Error; err != nil {
+			return nil, err
+		}
+		added = append(added, user.ID)
+	}
+
+	var removed []uint
+	for _, mention := range existing {
+		if !keep[mention.UserID] {
+			removed = append(removed, mention.ID)
+		}
+	}
+	if len(removed) > 0 {
+		if err := tx.Where("id IN (?)", removed).Delete(&models.CommentMention{}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return added, nil
+}
+
+// respondComment 返回单条评论
+func respondComment(c *gin.Context, comment models.Comment) {
+	items, err := buildCommentResponses([]models.Comment{comment})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取评论失败"})
+		return
+	}
+	c.JSON(http.StatusOK, items[0])
+}
+
+// buildCommentResponses 加载作者和提及的用户，渲染评论正文
+func buildCommentResponses(comments []models.Comment) ([]models.CommentResponse, error) {
+	responses := make([]models.CommentResponse, len(comments))
+	if len(comments) == 0 {
+		return responses, nil
+	}
+
+	commentIDs := make([]uint, len(comments))
+	userIDs := make([]uint, 0, len(comments))
+	for i, comment := range comments {
+		commentIDs[i] = comment.ID
+		userIDs = append(userIDs, comment.UserID)
+	}
+
+	var mentions []models.CommentMention
+	if err := db.Where("comment_id IN (?)", commentIDs).Order("id ASC").Find(&mentions).Error; err != nil {
+		return nil, err
+	}
+	for _, mention := range mentions {
+		userIDs = append(userIDs, mention.UserID)
+	}
+
+	users, err := loadUserSummaries(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	mentioned := make(map[uint][]models.UserSummary)
+	for _, mention := range mentions {
+		mentioned[mention.CommentID] = append(mentioned[mention.CommentID], users[mention.UserID])
+	}
+
+	for i, comment := range comments {
+		names := make([]string, 0, len(mentioned[comment.ID]))
+		for _, user := range mentioned[comment.ID] {
+			names = append(names, user.Username)
+		}
+		responses[i] = models.CommentResponse{
+			ID:        comment.ID,
+			TaskID:    comment.TaskID,
+			Author:    users[comment.UserID],
+			Body:      comment.Body,
+			BodyHTML:  markdown.Render(comment.Body, names),
+			Mentions:  append([]models.UserSummary{}, mentioned[comment.ID]...),
+			EditedAt:  comment.EditedAt,
+			CreatedAt: comment.CreatedAt,
+		}
+	}
+	return responses, nil
+}
@@ -0,0 +1,395 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/models"
+)
+
+// DependencyRequest 添加或移除任务依赖的请求结构
+type DependencyRequest struct {
+	BlockerID uint `json:"blockerId" binding:"required"` // 阻塞该任务的任务ID
+}
+
+// GetTaskDependencies 获取阻塞任务的任务和被任务阻塞的任务
+func GetTaskDependencies(c *gin.Context) {
+	task, ok := findTask(c, accessView)
+	if !ok {
+		return
+	}
+
+	tree, err := loadTaskTree([]uint{task.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务依赖失败"})
+		return
+	}
+	blockedBy, err := loadTaskResponses(tree.blockers[task.ID])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务依赖失败"})
+		return
+	}
+	blocks, err := loadTaskResponses(tree.dependents[task.ID])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务依赖失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TaskDependencyResponse{BlockedBy: blockedBy, Blocks: blocks})
+}
+
+// AddTaskDependency 添加阻塞任务，形成循环依赖时拒绝
+func AddTaskDependency(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	task, ok := findTask(c, accessEdit)
+	if !ok {
+		return
+	}
+
+	var dependencyReq DependencyRequest
+	if err := c.ShouldBindJSON(&dependencyReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	// 阻塞任务必须与该任务属于同一创建者和工作区，且当前用户可以查看
+	var blocker models.Task
+	if db.Where("id = ? AND user_id = ? AND workspace_id = ?", dependencyReq.BlockerID, task.UserID, task.WorkspaceID).First(&blocker).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "阻塞任务不存在或无权限"})
+		return
+	}
+	levels, err := taskAccessLevels(userID.(uint), []models.Task{blocker})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加任务依赖失败"})
+		return
+	}
+	if levels[blocker.ID] == accessNone {
+		c.JSON(http.StatusNotFound, gin.H{"error": "阻塞任务不存在或无权限"})
+		return
+	}
+	if hasDependency(task.ID, blocker.ID) {
+		respondTask(c, task)
+		return
+	}
+
+	tx := db.Begin()
+	// 先锁定创建者，同一创建者的依赖修改串行执行，避免并发添加的两条依赖各自通过检查后形成循环
+	if err := tx.Exec("UPDATE users SET updated_at = updated_at WHERE id = ?", task.UserID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加任务依赖失败"})
+		return
+	}
+	// 阻塞任务已直接或间接被该任务阻塞时，添加后会形成循环
+	path, err := blockerPath(tx, blocker.ID, task.ID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加任务依赖失败"})
+		return
+	}
+	if path != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "任务依赖不能形成循环",
+			"cycle": append([]uint{task.ID}, path...),
+		})
+		return
+	}
+	if err := changeDependencies(tx, &task, userID.(uint), func(tx *gorm.DB) error {
+		return tx.Create(&models.TaskDependency{UserID: task.UserID, TaskID: task.ID, BlockerID: blocker.ID}).Error
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加任务依赖失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加任务依赖失败"})
+		return
+	}
+	publishTaskEvent(streamTaskUpdated, task)
+
+	respondTask(c, task)
+}
+
+// RemoveTaskDependency 移除阻塞任务
+func RemoveTaskDependency(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	task, ok := findTask(c, accessEdit)
+	if !ok {
+		return
+	}
+
+	var dependencyReq DependencyRequest
+	if err := c.ShouldBindJSON(&dependencyReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if !hasDependency(task.ID, dependencyReq.BlockerID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务之间不存在该依赖"})
+		return
+	}
+
+	tx := db.Begin()
+	if err := changeDependencies(tx, &task, userID.(uint), func(tx *gorm.DB) error {
+		return tx.Where("task_id = ? AND blocker_id = ?", task.ID, dependencyReq.BlockerID).
+			Delete(&models.TaskDependency{}).Error
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除任务依赖失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除任务依赖失败"})
+		return
+	}
+	publishTaskEvent(streamTaskUpdated, task)
+
+	respondTask(c, task)
+}
+
+// GetTaskPlan 获取执行计划，按依赖关系对未完成的任务拓扑排序
+// 同一层级内按截止日期、优先级排序；支持projectId以及与任务列表相同的筛选参数
+func GetTaskPlan(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	query, ok := filterTaskProject(c, db.Where("user_id = ? AND workspace_id = ? AND completed = ?", userID, currentWorkspace(c), false))
+	if !ok {
+		return
+	}
+	query, ok = filterTasks(c, query)
+	if !ok {
+		return
+	}
+
+	var tasks []models.Task
+	if err := query.Preload("Tags").Preload("Attachments").Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取执行计划失败"})
+		return
+	}
+	tree, err := loadTaskTree(taskIDs(tasks))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取执行计划失败"})
+		return
+	}
+
+	// 按Kahn算法逐层取出没有待处理阻塞任务的任务，只考虑计划范围内的依赖
+	inPlan := make(map[uint]bool, len(tasks))
+	for _, task := range tasks {
+		inPlan[task.ID] = true
+	}
+	pending := make(map[uint]int, len(tasks))
+	var ready []uint
+	for _, task := range tasks {
+		for _, blocker := range tree.blockers[task.ID] {
+			if inPlan[blocker] {
+				pending[task.ID]++
+			}
+		}
+		if pending[task.ID] == 0 {
+			ready = append(ready, task.ID)
+		}
+	}
+	levels := make(map[uint]int, len(tasks))
+	for level := 0; len(ready) > 0; level++ {
+		var next []uint
+		for _, id := range ready {
+			levels[id] = level
+			for _, dependent := range tree.dependents[id] {
+				if !inPlan[dependent] {
+					continue
+				}
+				if pending[dependent]--; pending[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		ready = next
+	}
+
+	items := make([]models.TaskPlanItem, 0, len(tasks))
+	for _, task := range tasks {
+		level, ok := levels[task.ID]
+		if !ok {
+			// 添加依赖时已校验循环（包括回收站中的任务，恢复后也不会形成循环），正常情况下不会出现，
+			// 只用于兼容校验之前已存在的数据
+			log.Printf("任务 %d 处于循环依赖中，放到执行计划末尾", task.ID)
+			level = len(tasks)
+		}
+		item := models.TaskPlanItem{TaskResponse: toTaskResponse(task), Level: level}
+		tree.fill(&item.TaskResponse)
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Level != b.Level {
+			return a.Level < b.Level
+		}
+		if !sameTime(a.DueDate, b.DueDate) {
+			if a.DueDate == nil || b.DueDate == nil {
+				return b.DueDate == nil
+			}
+			return a.DueDate.Before(*b.DueDate)
+		}
+		if priorityWeight[a.Priority] != priorityWeight[b.Priority] {
+			return priorityWeight[a.Priority] > priorityWeight[b.Priority]
+		}
+		return a.ID < b.ID
+	})
+
+	c.JSON(http.StatusOK, models.TaskPlanResponse{Items: items})
+}
+
+// 优先级排序权重，越大越优先
+var priorityWeight = map[models.Priority]int{
+	models.High:   3,
+	models.Medium: 2,
+	models.Low:    1,
+}
+
+// checkOpenBlockers 在完成任务前检查是否有未完成的阻塞任务
+// 有阻塞时返回409及阻塞任务ID，请求带force=true时允许强制完成；返回false时已写入错误响应
+func checkOpenBlockers(c *gin.Context, task models.Task) bool {
+	if c.Query("force") == "true" {
+		return true
+	}
+	tree, err := loadTaskTree([]uint{task.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务失败"})
+		return false
+	}
+	if open := tree.openBlockers(task.ID); len(open) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "任务被未完成的任务阻塞，确认后可强制完成",
+			"blockers": open,
+		})
+		return false
+	}
+	return true
+}
+
+// hasDependency 判断任务是否已被该任务阻塞
+func hasDependency(taskID, blockerID uint) bool {
+	var count int
+	db.Model(&models.TaskDependency{}).Where("task_id = ? AND blocker_id = ?", taskID, blockerID).Count(&count)
+	return count > 0
+}
+
+// changeDependencies 在事务中修改任务的阻塞任务，更新任务版本号并写入变更记录
+func changeDependencies(tx *gorm.DB, task *models.Task, actorID uint, change func(tx *gorm.DB) error) error {
+	before, err := blockerTitles(tx, task.ID)
+	if err != nil {
+		return err
+	}
+	if err := change(tx); err != nil {
+		return err
+	}
+	after, err := blockerTitles(tx, task.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(task).Updates(map[string]interface{}{"version": bumpVersion}).Error; err != nil {
+		return err
+	}
+	task.Version++
+	changes := []models.FieldChange{{Field: "blockedBy", Old: before, New: after}}
+	return recordTaskEvent(tx, task.ID, actorID, models.TaskEventUpdated, changes)
+}
+
+// blockerTitles 返回阻塞任务的标题，按依赖添加顺序排列
+func blockerTitles(tx *gorm.DB, taskID uint) ([]string, error) {
+	titles := []string{}
+	err := tx.Table("tasks").
+		Joins("JOIN task_dependencies ON task_dependencies.blocker_id = tasks.id").
+		Where("task_dependencies.task_id = ? AND tasks.deleted_at IS NULL", taskID).
+		Order("task_dependencies.id ASC").
+		Pluck("tasks.title", &titles).Error
+	return titles, err
+}
+
+// loadTaskResponses 按给定顺序加载任务并生成响应
+func loadTaskResponses(ids []uint) ([]models.TaskResponse, error) {
+	responses := make([]models.TaskResponse, 0, len(ids))
+	if len(ids) == 0 {
+		return responses, nil
+	}
+
+	var tasks []models.Task
+	if err := db.Where("id IN (?)", ids).Preload("Tags").Preload("Attachments").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	tree, err := loadTaskTree(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+	for _, id := range ids {
+		task, ok := byID[id]
+		if !ok {
+			continue
+		}
+		response := toTaskResponse(task)
+		tree.fill(&response)
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// blockerPath 沿阻塞关系查找从 id 到 blocker 的路径，即 id 被谁阻塞、又被谁阻塞……直到 blocker
+// 逐层查询依赖关系，回收站中的任务也参与，因为恢复后依赖关系随之恢复；
+// 返回包含两端的任务ID，不存在时返回nil，id 与 blocker 相同时返回只含自身的路径
+func blockerPath(tx *gorm.DB, id, blocker uint) ([]uint, error) {
+	prev := map[uint]uint{id: 0}
+	frontier := []uint{id}
+	for len(frontier) > 0 {
+		for _, current := range frontier {
+			if current == blocker {
+				var path []uint
+				for ; current != 0; current = prev[current] {
+					path = append([]uint{current}, path...)
+				}
+				return path, nil
+			}
+		}
+
+		var edges []models.TaskDependency
+		if err := tx.Table("task_dependencies").
+			Select("task_id, blocker_id").
+			Where("task_id IN (?)", frontier).
+			Order("id ASC").
+			Scan(&edges).Error; err != nil {
+			return nil, err
+		}
+		frontier = nil
+		for _, edge := range edges {
+			if _, seen := prev[edge.BlockerID]; !seen {
+				prev[edge.BlockerID] = edge.TaskID
+				frontier = append(frontier, edge.BlockerID)
+			}
+		}
+	}
+	return nil, nil
+}
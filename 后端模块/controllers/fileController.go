@@ -2,28 +2,26 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7"
 
 	"taskmanager/config"
+	"taskmanager/models"
 )
 
-// FileResponse 文件响应结构
-type FileResponse struct {
-	FileName string `json:"fileName"` // 原始文件名
-	FileURL  string `json:"fileUrl"`  // 文件访问URL
-	FileSize int64  `json:"fileSize"` // 文件大小（字节）
-	FileType string `json:"fileType"` // 文件类型
-	UploadAt string `json:"uploadAt"` // 上传时间
-}
-
 // UploadFile 上传文件处理函数
 func UploadFile(c *gin.Context) {
 	// 从上下文中获取用户ID
@@ -33,18 +31,110 @@ func UploadFile(c *gin.Context) {
 		return
 	}
 
+	// 访客不能上传文件
+	if !requireMember(c) {
+		return
+	}
+
+	fileRecord, ok := receiveUpload(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	// 保存文件元数据
+	if err := db.Create(&fileRecord).Error; err != nil {
+		// 元数据写入失败时清理已上传的对象，避免产生孤儿对象
+		removeObject(fileRecord.ObjectKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件信息失败"})
+		return
+	}
+	publishFileEvent(streamFileUploaded, fileRecord)
+
+	c.JSON(http.StatusOK, toFileResponse(fileRecord))
+}
+
+// InitFiles 为升级前上传的文件补齐元数据
+// 旧版本的对象直接存放在存储桶根目录下，对象键为"<用户ID>_<UUID><扩展名>"，没有记录原始文件名；
+// 导入时以对象键中UUID之后的部分作为文件名，归入用户的个人工作区。已导入的对象不会重复导入
+func InitFiles() {
+	minioConfig := config.GetMinioConfig()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	imported := 0
+	for object := range config.MinioClient.ListObjects(ctx, minioConfig.Bucket, minio.ListObjectsOptions{}) {
+		if object.Err != nil {
+			log.Printf("列出存储桶中的对象失败: %v", object.Err)
+			return
+		}
+		userID, name, ok := parseLegacyObjectKey(object.Key)
+		if !ok {
+			continue
+		}
+
+		var count int
+		if err := db.Unscoped().Model(&models.File{}).Where("object_key = ?", object.Key).Count(&count).Error; err != nil {
+			log.Printf("检查对象 %s 是否已导入失败: %v", object.Key, err)
+			continue
+		}
+		if count > 0 {
+			continue
+		}
+		var workspace models.Workspace
+		if db.Where("owner_id = ? AND personal = ?", userID, true).First(&workspace).RecordNotFound() {
+			// 用户已不存在
+			continue
+		}
+
+		file := models.File{
+			UserID:       userID,
+			WorkspaceID:  workspace.ID,
+			OriginalName: name,
+			ObjectKey:    object.Key,
+			Size:         object.Size,
+			ContentType:  getContentType(filepath.Ext(object.Key)),
+		}
+		file.CreatedAt = object.LastModified
+		if err := db.Create(&file).Error; err != nil {
+			log.Printf("导入对象 %s 的元数据失败: %v", object.Key, err)
+			continue
+		}
+		imported++
+	}
+	if imported > 0 {
+		log.Printf("已为升级前上传的%d个文件补齐元数据", imported)
+	}
+}
+
+// parseLegacyObjectKey 解析旧版本的对象键"<用户ID>_<UUID><扩展名>"，返回用户ID和用作文件名的UUID部分
+// 头像（"avatar_"开头）和新版本"files/"下的对象不匹配
+func parseLegacyObjectKey(key string) (uint, string, bool) {
+	parts := strings.SplitN(key, "_", 2)
+	if len(parts) != 2 || parts[1] == "" || strings.Contains(key, "/") {
+		return 0, "", false
+	}
+	userID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil || userID == 0 {
+		return 0, "", false
+	}
+	return uint(userID), parts[1], true
+}
+
+// receiveUpload 校验请求中的文件并上传到MinIO，返回尚未保存的文件元数据
+// 失败时已写入错误响应；调用方保存元数据失败时需要调用removeObject清理对象
+func receiveUpload(c *gin.Context, userID uint) (models.File, bool) {
 	// 获取文件
 	file, fileHeader, err := c.Request.FormFile("file")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "获取文件失败"})
-		return
+		return models.File{}, false
 	}
 	defer file.Close()
 
 	// 检查文件大小（限制为10MB）
 	if fileHeader.Size > 10*1024*1024 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "文件大小不能超过10MB"})
-		return
+		return models.File{}, false
 	}
 
 	// 获取文件扩展名
@@ -59,44 +149,41 @@ func UploadFile(c *gin.Context) {
 
 	if !allowedExts[fileExt] {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的文件类型"})
-		return
+		return models.File{}, false
 	}
 
-	// 生成唯一的文件名
-	fileName := fmt.Sprintf("%d_%s%s", userID, uuid.New().String(), fileExt)
+	// 生成唯一的对象键，原始文件名保存在数据库中
+	objectKey := fmt.Sprintf("files/%d/%s%s", userID, uuid.New().String(), fileExt)
 
 	// 获取MinIO配置
 	minioConfig := config.GetMinioConfig()
 
-	// 上传文件到MinIO
+	// 上传文件到MinIO，同时计算校验和
 	contentType := getContentType(fileExt)
+	hasher := sha256.New()
 	_, err = config.MinioClient.PutObject(
 		context.Background(),
 		minioConfig.Bucket,
-		fileName,
-		file,
+		objectKey,
+		io.TeeReader(file, hasher),
 		fileHeader.Size,
 		minio.PutObjectOptions{ContentType: contentType},
 	)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "上传文件失败"})
-		return
-	}
-
-	// 构建文件URL
-	fileURL := fmt.Sprintf("http://%s/%s/%s", minioConfig.Endpoint, minioConfig.Bucket, fileName)
-
-	// 返回文件信息
-	response := FileResponse{
-		FileName: fileHeader.Filename,
-		FileURL:  fileURL,
-		FileSize: fileHeader.Size,
-		FileType: contentType,
-		UploadAt: time.Now().Format("2006-01-02 15:04:05"),
+		return models.File{}, false
 	}
 
-	c.JSON(http.StatusOK, response)
+	return models.File{
+		UserID:       userID,
+		WorkspaceID:  currentWorkspace(c),
+		OriginalName: filepath.Base(fileHeader.Filename),
+		ObjectKey:    objectKey,
+		Size:         fileHeader.Size,
+		ContentType:  contentType,
+		Checksum:     hex.EncodeToString(hasher.Sum(nil)),
+	}, true
 }
 
 // GetFileList 获取文件列表
@@ -108,48 +195,74 @@ func GetFileList(c *gin.Context) {
 		return
 	}
 
-	// 获取MinIO配置
-	minioConfig := config.GetMinioConfig()
-
-	// 创建一个前缀，只列出当前用户的文件
-	prefix := fmt.Sprintf("%d_", userID)
+	// 查询当前用户在当前工作区的文件
+	var files []models.File
+	if err := db.Where("user_id = ? AND workspace_id = ?", userID, currentWorkspace(c)).Order("created_at DESC").Find(&files).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件列表失败"})
+		return
+	}
 
-	// 列出对象
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	c.JSON(http.StatusOK, gin.H{"files": toFileResponses(files)})
+}
 
-	objectCh := config.MinioClient.ListObjects(ctx, minioConfig.Bucket, minio.ListObjectsOptions{
-		Prefix:    prefix,
-		Recursive: true,
-	})
+// DeleteFile 删除文件
+func DeleteFile(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
 
-	var fileList []FileResponse
-	for object := range objectCh {
-		if object.Err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件列表失败"})
-			return
-		}
+	// 获取文件ID
+	fileID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件ID"})
+		return
+	}
 
-		// 提取原始文件名（去掉UUID部分）
-		originalName := getOriginalFileName(object.Key)
+	// 查找文件，确保用户只能删除自己的文件
+	var file models.File
+	if db.Where("id = ? AND user_id = ? AND workspace_id = ?", fileID, userID, currentWorkspace(c)).First(&file).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在或无权限"})
+		return
+	}
 
-		// 构建文件URL
-		fileURL := fmt.Sprintf("http://%s/%s/%s", minioConfig.Endpoint, minioConfig.Bucket, object.Key)
+	// 先删除文件元数据，并从引用它的任务中移除
+	tx := db.Begin()
+	taskIDs, err := detachFile(tx, file)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除文件失败"})
+		return
+	}
+	if err := tx.Delete(&file).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除文件失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除文件失败"})
+		return
+	}
 
-		fileList = append(fileList, FileResponse{
-			FileName: originalName,
-			FileURL:  fileURL,
-			FileSize: object.Size,
-			FileType: getContentType(filepath.Ext(object.Key)),
-			UploadAt: object.LastModified.Format("2006-01-02 15:04:05"),
-		})
+	// 元数据删除后再删除MinIO中的对象，失败时只记录日志，不会留下指向不存在对象的记录
+	removeObject(file.ObjectKey)
+	publishFileEvent(streamFileDeleted, file)
+	if len(taskIDs) > 0 {
+		var tasks []models.Task
+		if err := db.Where("id IN (?)", taskIDs).Find(&tasks).Error; err != nil {
+			log.Printf("加载附件所在的任务失败: %v", err)
+		}
+		publishTaskEvents(streamTaskUpdated, tasks)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"files": fileList})
+	c.JSON(http.StatusOK, gin.H{"message": "文件删除成功"})
 }
 
-// DeleteFile 删除文件
-func DeleteFile(c *gin.Context) {
+// DownloadFile 下载文件
+// 校验文件归属后重定向到限时有效的预签名链接
+func DownloadFile(c *gin.Context) {
 	// 从上下文中获取用户ID
 	userID, exists := c.Get("userId")
 	if !exists {
@@ -157,37 +270,87 @@ func DeleteFile(c *gin.Context) {
 		return
 	}
 
-	// 获取文件名
-	fileName := c.Param("fileName")
-	if fileName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "文件名不能为空"})
+	// 获取文件ID
+	fileID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件ID"})
 		return
 	}
 
-	// 检查文件名是否以用户ID开头，确保用户只能删除自己的文件
-	prefix := fmt.Sprintf("%d_", userID)
-	if !strings.HasPrefix(fileName, prefix) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "无权删除此文件"})
+	// 查找文件，确保用户只能下载自己的文件
+	var file models.File
+	if db.Where("id = ? AND user_id = ? AND workspace_id = ?", fileID, userID, currentWorkspace(c)).First(&file).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在或无权限"})
 		return
 	}
 
-	// 获取MinIO配置
+	fileURL := presignedURL(file.ObjectKey, file.OriginalName)
+	if fileURL == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成下载链接失败"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, fileURL)
+}
+
+// toFileResponse 将文件模型转换为响应结构
+func toFileResponse(file models.File) models.FileResponse {
+	return models.FileResponse{
+		ID:       file.ID,
+		FileName: file.OriginalName,
+		FileURL:  presignedURL(file.ObjectKey, file.OriginalName),
+		FileSize: file.Size,
+		FileType: file.ContentType,
+		UploadAt: file.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// toFileResponses 将文件模型列表转换为响应结构
+func toFileResponses(files []models.File) []models.FileResponse {
+	responses := make([]models.FileResponse, len(files))
+	for i, file := range files {
+		responses[i] = toFileResponse(file)
+	}
+	return responses
+}
+
+// presignedURL 为对象生成限时有效的预签名GET链接，失败时返回空字符串
+// downloadName 不为空时，下载时使用该文件名
+func presignedURL(objectKey, downloadName string) string {
 	minioConfig := config.GetMinioConfig()
 
-	// 删除文件
+	reqParams := make(url.Values)
+	if downloadName != "" {
+		reqParams.Set("response-content-disposition", mime.FormatMediaType("inline", map[string]string{"filename": downloadName}))
+	}
+
+	presigned, err := config.MinioClient.PresignedGetObject(
+		context.Background(),
+		minioConfig.Bucket,
+		objectKey,
+		minioConfig.PresignTTL,
+		reqParams,
+	)
+	if err != nil {
+		log.Printf("生成对象 %s 的预签名链接失败: %v", objectKey, err)
+		return ""
+	}
+	return presigned.String()
+}
+
+// removeObject 删除MinIO中的对象
+func removeObject(objectKey string) error {
+	minioConfig := config.GetMinioConfig()
 	err := config.MinioClient.RemoveObject(
 		context.Background(),
 		minioConfig.Bucket,
-		fileName,
+		objectKey,
 		minio.RemoveObjectOptions{},
 	)
-
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除文件失败"})
-		return
+		log.Printf("删除对象 %s 失败: %v", objectKey, err)
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "文件删除成功"})
+	return err
 }
 
 // 根据文件扩展名获取内容类型
@@ -211,14 +374,3 @@ func getContentType(fileExt string) string {
 		return "application/octet-stream"
 	}
 }
-
-// 从存储的文件名中提取原始文件名
-func getOriginalFileName(key string) string {
-	// 文件名格式为: userID_UUID.ext
-	// 由于我们没有存储原始文件名，这里只返回UUID部分
-	parts := strings.Split(key, "_")
-	if len(parts) < 2 {
-		return key
-	}
-	return parts[1]
-}
@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/models"
+)
+
+// GetTaskHistory 获取任务的变更历史，按时间倒序
+// 有权查看任务的用户都可以查看，回收站中的任务同样可以查看
+func GetTaskHistory(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 获取任务ID
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	// 解析分页参数
+	pageSize, err := parsePageSize(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := parsePageNumber(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 查找任务，回收站中的任务只有创建者可以查看
+	var task models.Task
+	if db.Unscoped().Where("id = ? AND workspace_id = ?", taskID, currentWorkspace(c)).First(&task).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在或无权限"})
+		return
+	}
+	levels, err := taskAccessLevels(userID.(uint), []models.Task{task})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务历史失败"})
+		return
+	}
+	if levels[task.ID] == accessNone || (task.DeletedAt != nil && levels[task.ID] != accessOwner) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在或无权限"})
+		return
+	}
+
+	query := db.Model(&models.TaskEvent{}).Where("task_id = ?", task.ID)
+	var total int
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务历史失败"})
+		return
+	}
+
+	var events []models.TaskEvent
+	if err := query.Order("created_at DESC").Order("id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务历史失败"})
+		return
+	}
+
+	// 加载操作人信息
+	actorIDs := make([]uint, 0, len(events))
+	for _, event := range events {
+		actorIDs = append(actorIDs, event.UserID)
+	}
+	actors, err := loadUserSummaries(actorIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务历史失败"})
+		return
+	}
+
+	result := models.TaskHistoryResponse{
+		Items:    make([]models.TaskEventResponse, len(events)),
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	for i, event := range events {
+		changes := []models.FieldChange{}
+		if event.Changes != "" {
+			if err := json.Unmarshal([]byte(event.Changes), &changes); err != nil {
+				log.Printf("解析任务变更记录失败: %v", err)
+			}
+		}
+		result.Items[i] = models.TaskEventResponse{
+			ID:        event.ID,
+			TaskID:    event.TaskID,
+			Action:    event.Action,
+			Actor:     actors[event.UserID],
+			Changes:   changes,
+			CreatedAt: event.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// recordTaskEvent 写入一条任务变更记录
+// 在事务中调用时传入事务，保证记录与修改同时生效
+func recordTaskEvent(tx *gorm.DB, taskID, actorID uint, action string, changes []models.FieldChange) error {
+	event := models.TaskEvent{
+		TaskID: taskID,
+		UserID: actorID,
+		Action: action,
+	}
+	if len(changes) > 0 {
+		data, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		event.Changes = string(data)
+	}
+	return tx.Create(&event).Error
+}
+
+// recordTaskEvents 为多个任务写入相同的变更记录
+func recordTaskEvents(tx *gorm.DB, taskIDs []uint, actorID uint, action string, changes []models.FieldChange) error {
+	for _, taskID := range taskIDs {
+		if err := recordTaskEvent(tx, taskID, actorID, action, changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffTask 比较任务修改前后的字段，返回发生变化的字段
+// 标签只在修改前后都已加载时比较
+func diffTask(before, after models.Task) []models.FieldChange {
+	var changes []models.FieldChange
+	add := func(field string, oldValue, newValue interface{}) {
+		changes = append(changes, models.FieldChange{Field: field, Old: oldValue, New: newValue})
+	}
+
+	if before.Title != after.Title {
+		add("title", before.Title, after.Title)
+	}
+	if before.Description != after.Description {
+		add("description", before.Description, after.Description)
+	}
+	if before.Completed != after.Completed {
+		add("completed", before.Completed, after.Completed)
+	}
+	if before.Status != after.Status {
+		add("status", before.Status, after.Status)
+	}
+	if before.Priority != after.Priority {
+		add("priority", before.Priority, after.Priority)
+	}
+	if !sameTime(before.DueDate, after.DueDate) {
+		add("dueDate", before.DueDate, after.DueDate)
+	}
+	if !sameID(before.ParentID, after.ParentID) {
+		add("parentId", before.ParentID, after.ParentID)
+	}
+	if !sameID(before.ProjectID, after.ProjectID) {
+		add("projectId", before.ProjectID, after.ProjectID)
+	}
+	if before.Recurrence != after.Recurrence {
+		add("recurrence", before.Recurrence, after.Recurrence)
+	}
+	if before.Tags != nil && after.Tags != nil {
+		oldNames, newNames := namesOfTags(before.Tags), namesOfTags(after.Tags)
+		if !sameStrings(oldNames, newNames) {
+			add("tags", oldNames, newNames)
+		}
+	}
+	return changes
+}
+
+// namesOfTags 返回标签名称列表
+func namesOfTags(tags []models.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// sameStrings 判断两个字符串集合是否相同，不考虑顺序
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[string]bool, len(a))
+	for _, s := range a {
+		seen[s] = true
+	}
+	for _, s := range b {
+		if !seen[s] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameTime 判断两个可空时间是否相同
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// sameID 判断两个可空ID是否相同
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
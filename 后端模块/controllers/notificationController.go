@@ -0,0 +1,320 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/models"
+)
+
+// 通知标题和正文中评论摘录的最大长度（字符数），与通知表的字段长度一致
+const (
+	notificationTitleLength   = 200
+	notificationExcerptLength = 200
+)
+
+// GetNotifications 获取当前用户的通知，按时间倒序，unread=true时只返回未读通知
+func GetNotifications(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 解析分页参数
+	pageSize, err := parsePageSize(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := parsePageNumber(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	unread, err := countUnreadNotifications(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通知失败"})
+		return
+	}
+
+	query := db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	var total int
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通知失败"})
+		return
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC").Order("id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通知失败"})
+		return
+	}
+
+	items, err := buildNotificationResponses(notifications)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通知失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NotificationListResponse{
+		Items:    items,
+		Total:    total,
+		Unread:   unread,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// GetUnreadNotificationCount 获取当前用户的未读通知数
+func GetUnreadNotificationCount(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	count, err := countUnreadNotifications(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取未读通知数失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"count": count})
+}
+
+// MarkNotificationRead 将通知标记为已读，已读的通知保留原已读时间
+func MarkNotificationRead(c *gin.Context) {
+	notification, ok := findOwnNotification(c)
+	if !ok {
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := db.Model(&notification).Update("read_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "标记通知失败"})
+			return
+		}
+		notification.ReadAt = &now
+	}
+	respondNotification(c, notification)
+}
+
+// MarkNotificationUnread 将通知标记为未读
+func MarkNotificationUnread(c *gin.Context) {
+	notification, ok := findOwnNotification(c)
+	if !ok {
+		return
+	}
+
+	if notification.ReadAt != nil {
+		if err := db.Model(&notification).Updates(map[string]interface{}{"read_at": nil}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "标记通知失败"})
+			return
+		}
+		notification.ReadAt = nil
+	}
+	respondNotification(c, notification)
+}
+
+// MarkAllNotificationsRead 将当前用户的全部未读通知标记为已读
+func MarkAllNotificationsRead(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	result := db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "标记通知失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已全部标记为已读", "count": result.RowsAffected})
+}
+
+// findOwnNotification 根据URL参数查找当前用户的通知，失败时已写入错误响应
+func findOwnNotification(c *gin.Context) (models.Notification, bool) {
+	var notification models.Notification
+
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return notification, false
+	}
+
+	// 获取通知ID
+	notificationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的通知ID"})
+		return notification, false
+	}
+
+	if db.Where("id = ? AND user_id = ?", notificationID, userID).First(&notification).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "通知不存在"})
+		return notification, false
+	}
+	return notification, true
+}
+
+// countUnreadNotifications 统计用户的未读通知数
+func countUnreadNotifications(userID uint) (int, error) {
+	var count int
+	err := db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// respondNotification 返回单条通知
+func respondNotification(c *gin.Context, notification models.Notification) {
+	items, err := buildNotificationResponses([]models.Notification{notification})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通知失败"})
+		return
+	}
+	c.JSON(http.StatusOK, items[0])
+}
+
+// buildNotificationResponses 加载触发通知的用户，生成通知响应
+func buildNotificationResponses(notifications []models.Notification) ([]models.NotificationResponse, error) {
+	responses := make([]models.NotificationResponse, len(notifications))
+
+	var actorIDs []uint
+	for _, notification := range notifications {
+		if notification.ActorID != nil {
+			actorIDs = append(actorIDs, *notification.ActorID)
+		}
+	}
+	actors, err := loadUserSummaries(actorIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, notification := range notifications {
+		responses[i] = models.NotificationResponse{
+			ID:        notification.ID,
+			Type:      notification.Type,
+			TaskID:    notification.TaskID,
+			Title:     notification.Title,
+			Body:      notification.Body,
+			Read:      notification.ReadAt != nil,
+			ReadAt:    notification.ReadAt,
+			CreatedAt: notification.CreatedAt,
+		}
+		if notification.ActorID != nil {
+			actor := actors[*notification.ActorID]
+			responses[i].Actor = &actor
+		}
+	}
+	return responses, nil
+}
+
+// notifyTaskUsers 为任务相关的一组用户各写入一条通知
+// 跳过触发者本人、重复的用户以及无权查看任务的用户，避免通过通知泄露任务内容
+func notifyTaskUsers(tx *gorm.DB, task models.Task, actorID uint, userIDs []uint, kind, title, body string) error {
+	notified := map[uint]bool{actorID: true}
+	for _, userID := range userIDs {
+		if notified[userID] {
+			continue
+		}
+		notified[userID] = true
+
+		levels, err := taskAccessLevels(userID, []models.Task{task})
+		if err != nil {
+			return err
+		}
+		if levels[task.ID] == accessNone {
+			continue
+		}
+		if err := createTaskNotification(tx, task, actorID, userID, kind, title, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createTaskNotification 给一个用户写入一条与任务相关的通知
+func createTaskNotification(tx *gorm.DB, task models.Task, actorID, userID uint, kind, title, body string) error {
+	taskID := task.ID
+	return tx.Create(&models.Notification{
+		UserID:  userID,
+		ActorID: &actorID,
+		Type:    kind,
+		TaskID:  &taskID,
+		Title:   excerpt(title, notificationTitleLength),
+		Body:    body,
+	}).Error
+}
+
+// notifyAssigned 通知用户被设为任务负责人，负责人自己添加自己时不通知
+func notifyAssigned(tx *gorm.DB, task models.Task, actorID, assigneeID uint) error {
+	if actorID == assigneeID {
+		return nil
+	}
+	var actor models.User
+	if err := tx.Select("id, username").First(&actor, actorID).Error; err != nil {
+		return err
+	}
+	return createTaskNotification(tx, task, actorID, assigneeID, models.NotificationAssigned,
+		actor.Username+" 将任务「"+task.Title+"」分配给了你", "")
+}
+
+// notifyComment 通知新评论：新提及的用户收到提及通知，任务创建者和负责人收到评论通知
+// 同时被提及的创建者或负责人只收到提及通知
+func notifyComment(tx *gorm.DB, task models.Task, comment models.Comment, mentioned []uint, created bool) error {
+	var author models.User
+	if err := tx.Select("id, username").First(&author, comment.UserID).Error; err != nil {
+		return err
+	}
+	body := excerpt(comment.Body, notificationExcerptLength)
+
+	if err := notifyTaskUsers(tx, task, author.ID, mentioned, models.NotificationMention,
+		author.Username+" 在任务「"+task.Title+"」的评论中提到了你", body); err != nil {
+		return err
+	}
+	if !created {
+		return nil
+	}
+
+	skip := make(map[uint]bool, len(mentioned))
+	for _, id := range mentioned {
+		skip[id] = true
+	}
+	var assignees []uint
+	if err := tx.Model(&models.TaskAssignee{}).Where("task_id = ?", task.ID).Order("id ASC").
+		Pluck("user_id", &assignees).Error; err != nil {
+		return err
+	}
+	var watchers []uint
+	for _, id := range append([]uint{task.UserID}, assignees...) {
+		if !skip[id] {
+			watchers = append(watchers, id)
+		}
+	}
+	return notifyTaskUsers(tx, task, author.ID, watchers, models.NotificationComment,
+		author.Username+" 评论了任务「"+task.Title+"」", body)
+}
+
+// excerpt 截取文本的前n个字符，超出时末尾以省略号代替
+func excerpt(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	return string([]rune(text)[:n-1]) + "…"
+}
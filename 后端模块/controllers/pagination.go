@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/models"
+)
+
+// 分页默认值
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// 截止日期为空的任务始终排在最后，排序时用极值代替空值
+const (
+	nullDueDateLast  = "9999-12-31 23:59:59"
+	nullDueDateFirst = "0001-01-01 00:00:00"
+)
+
+// taskSortField 可排序字段
+// expr 为排序表达式，value 从任务中取出对应的游标值
+type taskSortField struct {
+	expr  func(desc bool) string
+	value func(task models.Task, desc bool) interface{}
+	time  bool // 游标值是否为时间
+}
+
+// taskSortFields 排序字段白名单
+var taskSortFields = map[string]taskSortField{
+	"dueDate": {
+		expr: func(desc bool) string {
+			if desc {
+				return "COALESCE(due_date, '" + nullDueDateFirst + "')"
+			}
+			return "COALESCE(due_date, '" + nullDueDateLast + "')"
+		},
+		value: func(task models.Task, desc bool) interface{} {
+			if task.DueDate != nil {
+				return *task.DueDate
+			}
+			// 空值直接使用与排序表达式相同的字面量，避免不同数据库的时间格式差异
+			if desc {
+				return nullDueDateFirst
+			}
+			return nullDueDateLast
+		},
+		time: true,
+	},
+	"priority": {
+		expr: func(bool) string {
+			return "CASE priority WHEN 'high' THEN 3 WHEN 'medium' THEN 2 ELSE 1 END"
+		},
+		value: func(task models.Task, _ bool) interface{} {
+			switch task.Priority {
+			case models.High:
+				return 3
+			case models.Medium:
+				return 2
+			default:
+				return 1
+			}
+		},
+	},
+	"createdAt": {
+		expr:  func(bool) string { return "created_at" },
+		value: func(task models.Task, _ bool) interface{} { return task.CreatedAt },
+		time:  true,
+	},
+	"updatedAt": {
+		expr:  func(bool) string { return "updated_at" },
+		value: func(task models.Task, _ bool) interface{} { return task.UpdatedAt },
+		time:  true,
+	},
+	"title": {
+		expr:  func(bool) string { return "title" },
+		value: func(task models.Task, _ bool) interface{} { return task.Title },
+	},
+}
+
+// taskSort 解析后的排序方式
+type taskSort struct {
+	key   string // 规范化的排序参数，如 "-priority"
+	field taskSortField
+	desc  bool
+}
+
+// taskPage 解析后的分页参数
+type taskPage struct {
+	sort     taskSort
+	page     int
+	pageSize int
+	cursor   *taskCursor
+}
+
+// taskCursor 游标内容，记录上一页最后一条的排序值和ID
+type taskCursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"`
+	ID    uint        `json:"id"`
+}
+
+// parseTaskPage 解析 sort、page、pageSize、cursor 查询参数
+// sort 取值为白名单字段名，前缀 "-" 表示降序，默认按截止日期升序
+func parseTaskPage(c *gin.Context) (*taskPage, error) {
+	key := c.DefaultQuery("sort", "dueDate")
+	name := strings.TrimPrefix(key, "-")
+	field, ok := taskSortFields[name]
+	if !ok {
+		return nil, fmt.Errorf("无效的排序字段，可选值为: dueDate, priority, createdAt, updatedAt, title，前缀-表示降序")
+	}
+	p := &taskPage{
+		sort:     taskSort{key: key, field: field, desc: strings.HasPrefix(key, "-")},
+		page:     1,
+		pageSize: defaultPageSize,
+	}
+
+	pageSize, err := parsePageSize(c)
+	if err != nil {
+		return nil, err
+	}
+	p.pageSize = pageSize
+
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := decodeTaskCursor(v, p.sort)
+		if err != nil {
+			return nil, err
+		}
+		p.cursor = cursor
+	} else if p.page, err = parsePageNumber(c); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// parsePageSize 解析pageSize参数，省略时返回默认值
+func parsePageSize(c *gin.Context) (int, error) {
+	v := c.Query("pageSize")
+	if v == "" {
+		return defaultPageSize, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > maxPageSize {
+		return 0, fmt.Errorf("无效的pageSize，取值范围为1-%d", maxPageSize)
+	}
+	return n, nil
+}
+
+// parsePageNumber 解析page参数，从1开始，省略时返回1
+func parsePageNumber(c *gin.Context) (int, error) {
+	v := c.Query("page")
+	if v == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, errors.New("无效的page")
+	}
+	return n, nil
+}
+
+// apply 在查询上应用排序、游标条件和分页，多取一条用于判断是否还有下一页
+func (p *taskPage) apply(query *gorm.DB) *gorm.DB {
+	expr := p.sort.field.expr(p.sort.desc)
+	dir, cmp := "ASC", ">"
+	if p.sort.desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if p.cursor != nil {
+		query = query.Where(
+			fmt.Sprintf("(%s %s ?) OR (%s = ? AND id < ?)", expr, cmp, expr),
+			p.cursor.Value, p.cursor.Value, p.cursor.ID,
+		)
+	} else if p.page > 1 {
+		query = query.Offset((p.page - 1) * p.pageSize)
+	}
+
+	// ID降序作为并列时的次序，保证翻页稳定
+	return query.Order(expr + " " + dir).Order("id DESC").Limit(p.pageSize + 1)
+}
+
+// nextCursor 根据本页最后一条任务生成下一页的游标
+func (p *taskPage) nextCursor(last models.Task) string {
+	value := p.sort.field.value(last, p.sort.desc)
+	if t, ok := value.(time.Time); ok {
+		value = t.UTC().Format(time.RFC3339Nano)
+	}
+	data, _ := json.Marshal(taskCursor{Sort: p.sort.key, Value: value, ID: last.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeTaskCursor 解析游标，并校验其与当前排序方式一致
+func decodeTaskCursor(value string, sort taskSort) (*taskCursor, error) {
+	invalid := errors.New("无效的cursor")
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, invalid
+	}
+	var cursor taskCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort.key {
+		return nil, invalid
+	}
+
+	// JSON中的时间和数字需要还原为查询可用的类型
+	switch v := cursor.Value.(type) {
+	case string:
+		if sort.field.time && v != nullDueDateLast && v != nullDueDateFirst {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, invalid
+			}
+			cursor.Value = t
+		}
+	case float64:
+		cursor.Value = int(v)
+	default:
+		return nil, invalid
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import "encoding/json"
+
+// patchField 记录JSON字段是否出现及其原始值，用于区分"省略"和"显式null"
+// 省略的字段 Set 为false；出现的字段（包括null）Set 为true
+type patchField struct {
+	Set bool
+	Raw json.RawMessage
+}
+
+// UnmarshalJSON 实现json.Unmarshaler，字段为null时同样会被调用
+func (f *patchField) UnmarshalJSON(data []byte) error {
+	f.Set = true
+	f.Raw = append(f.Raw[:0], data...)
+	return nil
+}
+
+// IsNull 判断字段是否显式设置为null
+func (f patchField) IsNull() bool {
+	return f.Set && string(f.Raw) == "null"
+}
+
+// stringValue 解析字符串值，null视为空字符串
+func (f patchField) stringValue() (string, error) {
+	var value string
+	if f.IsNull() {
+		return value, nil
+	}
+	err := json.Unmarshal(f.Raw, &value)
+	return value, err
+}
+
+// boolValue 解析布尔值，null视为false
+func (f patchField) boolValue() (bool, error) {
+	var value bool
+	if f.IsNull() {
+		return value, nil
+	}
+	err := json.Unmarshal(f.Raw, &value)
+	return value, err
+}
+
+// stringsValue 解析字符串数组，null视为空数组
+func (f patchField) stringsValue() ([]string, error) {
+	value := []string{}
+	if f.IsNull() {
+		return value, nil
+	}
+	err := json.Unmarshal(f.Raw, &value)
+	return value, err
+}
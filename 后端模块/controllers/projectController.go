@@ -0,0 +1,414 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/models"
+)
+
+// ProjectRequest 项目请求结构
+// 更新时省略的字段保持不变，因此布尔和数值字段使用指针
+type ProjectRequest struct {
+	Name        string  `json:"name"`        // 项目名称，创建时必填
+	Description *string `json:"description"` // 项目描述，可选
+	Color       string  `json:"color"`       // 项目颜色，#RRGGBB，可选
+	Archived    *bool   `json:"archived"`    // 是否归档，可选
+	SortOrder   *int    `json:"sortOrder"`   // 排序，可选
+}
+
+// projectCount 项目任务计数
+type projectCount struct {
+	ProjectID      uint
+	TaskCount      int
+	CompletedCount int
+}
+
+// GetProjects 获取当前用户的项目列表
+// 默认不返回已归档项目，archived=true时只返回已归档项目，archived=all时返回全部
+func GetProjects(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	query := db.Where("user_id = ? AND workspace_id = ?", userID, currentWorkspace(c))
+	switch c.Query("archived") {
+	case "", "false":
+		query = query.Where("archived = ?", false)
+	case "true":
+		query = query.Where("archived = ?", true)
+	case "all":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的归档筛选，可选值为: true, false, all"})
+		return
+	}
+
+	var projects []models.Project
+	if err := query.Order("sort_order ASC, created_at ASC").Find(&projects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取项目失败"})
+		return
+	}
+
+	counts, err := loadProjectCounts(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取项目失败"})
+		return
+	}
+
+	response := make([]models.ProjectResponse, len(projects))
+	for i, project := range projects {
+		response[i] = toProjectResponse(project, counts[project.ID])
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateProject 创建项目
+func CreateProject(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 访客不能创建项目
+	if !requireMember(c) {
+		return
+	}
+
+	// 绑定请求数据
+	var projectReq ProjectRequest
+	if err := c.ShouldBindJSON(&projectReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的项目数据"})
+		return
+	}
+
+	project := models.Project{
+		UserID:      userID.(uint),
+		WorkspaceID: currentWorkspace(c),
+		Color:       defaultTagColor,
+	}
+	if strings.TrimSpace(projectReq.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "项目名称不能为空"})
+		return
+	}
+	if !applyProjectRequest(c, &project, projectReq) {
+		return
+	}
+
+	if err := db.Create(&project).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建项目失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toProjectResponse(project, projectCount{}))
+}
+
+// UpdateProject 更新项目
+func UpdateProject(c *gin.Context) {
+	project, ok := findProject(c)
+	if !ok {
+		return
+	}
+
+	// 绑定请求数据
+	var projectReq ProjectRequest
+	if err := c.ShouldBindJSON(&projectReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的项目数据"})
+		return
+	}
+	if !applyProjectRequest(c, &project, projectReq) {
+		return
+	}
+
+	if err := db.Save(&project).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新项目失败"})
+		return
+	}
+
+	counts, _ := loadProjectCounts(project.UserID)
+	c.JSON(http.StatusOK, toProjectResponse(project, counts[project.ID]))
+}
+
+// DeleteProject 删除项目
+// 项目下的任务不会被删除，而是移出项目并改用默认工作流
+func DeleteProject(c *gin.Context) {
+	project, ok := findProject(c)
+	if !ok {
+		return
+	}
+
+	// 回收站中的任务同样移出项目，恢复后不会指向已删除的项目
+	tx := db.Begin()
+	var taskIDs []uint
+	if err := tx.Unscoped().Model(&models.Task{}).Where("project_id = ?", project.ID).Pluck("id", &taskIDs).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除项目失败"})
+		return
+	}
+	if err := tx.Unscoped().Model(&models.Task{}).Where("project_id = ?", project.ID).Updates(map[string]interface{}{
+		"project_id": nil,
+		"version":    bumpVersion,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除项目失败"})
+		return
+	}
+	changes := []models.FieldChange{{Field: "projectId", Old: project.ID, New: nil}}
+	if err := recordTaskEvents(tx, taskIDs, project.UserID, models.TaskEventMoved, changes); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除项目失败"})
+		return
+	}
+	if err := tx.Where("project_id = ?", project.ID).Delete(&models.WorkflowState{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除项目失败"})
+		return
+	}
+	if err := syncTaskStatuses(tx, project.UserID, taskIDs); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除项目失败"})
+		return
+	}
+	if err := tx.Delete(&project).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除项目失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除项目失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "项目已删除"})
+}
+
+// GetProjectTasks 获取项目下的任务，支持与任务列表相同的筛选参数
+func GetProjectTasks(c *gin.Context) {
+	project, ok := findProject(c)
+	if !ok {
+		return
+	}
+
+	query := db.Where("user_id = ? AND project_id = ?", project.UserID, project.ID)
+	listTasks(c, project.UserID, query)
+}
+
+// MoveTaskToProjectRequest 移动任务到项目的请求结构
+type MoveTaskToProjectRequest struct {
+	ProjectID *uint `json:"projectId"` // 目标项目ID，为空表示移出项目
+	Version   *int  `json:"version"`   // 客户端持有的版本号，也可以通过If-Match请求头提供
+}
+
+// MoveTaskToProject 将任务及其全部子任务移动到另一个项目
+// 目标项目的工作流中没有任务当前的状态时，改为同一完成状态的第一个状态
+func MoveTaskToProject(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 获取任务ID
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	// 查找任务
+	var task models.Task
+	if db.Where("id = ? AND user_id = ? AND workspace_id = ?", taskID, userID, currentWorkspace(c)).First(&task).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在或无权限"})
+		return
+	}
+
+	// 绑定请求数据
+	var moveReq MoveTaskToProjectRequest
+	if err := c.ShouldBindJSON(&moveReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if moveReq.ProjectID != nil && !ownsProject(userID, task.WorkspaceID, *moveReq.ProjectID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "项目不存在或无权限"})
+		return
+	}
+	version, ok := requestedTaskVersion(c, moveReq.Version)
+	if !ok {
+		return
+	}
+
+	tree, err := loadTaskTree([]uint{task.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移动任务失败"})
+		return
+	}
+	ids := append([]uint{task.ID}, tree.descendants(task.ID)...)
+
+	// 记录原项目，只为项目发生变化的任务写入变更记录
+	var before []models.Task
+	if err := db.Select("id, project_id").Where("id IN (?)", ids).Find(&before).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移动任务失败"})
+		return
+	}
+
+	// 任务本身仅当版本号与客户端持有的一致时才移动，子任务随之移动
+	tx := db.Begin()
+	query := tx.Model(&models.Task{}).Where("id = ?", task.ID)
+	if version != nil {
+		query = query.Where("version = ?", *version)
+	}
+	update := map[string]interface{}{
+		"project_id": moveReq.ProjectID,
+		"version":    bumpVersion,
+	}
+	result := query.Updates(update)
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移动任务失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		respondTaskConflict(c, task.ID)
+		return
+	}
+	if descendants := ids[1:]; len(descendants) > 0 {
+		if err := tx.Model(&models.Task{}).Where("id IN (?)", descendants).Updates(update).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "移动任务失败"})
+			return
+		}
+	}
+	for _, old := range before {
+		if sameID(old.ProjectID, moveReq.ProjectID) {
+			continue
+		}
+		changes := []models.FieldChange{{Field: "projectId", Old: old.ProjectID, New: moveReq.ProjectID}}
+		if err := recordTaskEvent(tx, old.ID, userID.(uint), models.TaskEventMoved, changes); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "移动任务失败"})
+			return
+		}
+	}
+	if err := syncTaskStatuses(tx, userID.(uint), ids); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移动任务失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移动任务失败"})
+		return
+	}
+	if err := db.First(&task, task.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移动任务失败"})
+		return
+	}
+	var moved []models.Task
+	if err := db.Where("id IN (?)", ids).Find(&moved).Error; err != nil {
+		log.Printf("加载移动的任务失败: %v", err)
+	}
+	publishTaskEvents(streamTaskUpdated, moved)
+
+	respondTask(c, task)
+}
+
+// findProject 根据URL参数查找当前用户的项目，失败时已写入错误响应
+func findProject(c *gin.Context) (models.Project, bool) {
+	var project models.Project
+
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return project, false
+	}
+
+	// 获取项目ID
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的项目ID"})
+		return project, false
+	}
+
+	if db.Where("id = ? AND user_id = ? AND workspace_id = ?", projectID, userID, currentWorkspace(c)).First(&project).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "项目不存在或无权限"})
+		return project, false
+	}
+	return project, true
+}
+
+// ownsProject 判断项目是否属于该用户且位于指定工作区
+func ownsProject(userID interface{}, workspaceID, projectID uint) bool {
+	var count int
+	db.Model(&models.Project{}).Where("id = ? AND user_id = ? AND workspace_id = ?", projectID, userID, workspaceID).Count(&count)
+	return count > 0
+}
+
+// applyProjectRequest 校验请求数据并写入项目模型，校验失败时已写入错误响应
+func applyProjectRequest(c *gin.Context, project *models.Project, req ProjectRequest) bool {
+	if name := strings.TrimSpace(req.Name); name != "" {
+		project.Name = name
+	}
+	if req.Description != nil {
+		project.Description = *req.Description
+	}
+	if req.Color != "" {
+		if !tagColorPattern.MatchString(req.Color) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的颜色，格式为 #RRGGBB"})
+			return false
+		}
+		project.Color = req.Color
+	}
+	if req.Archived != nil {
+		project.Archived = *req.Archived
+	}
+	if req.SortOrder != nil {
+		project.SortOrder = *req.SortOrder
+	}
+	return true
+}
+
+// loadProjectCounts 统计用户各项目的任务总数和已完成数
+func loadProjectCounts(userID interface{}) (map[uint]projectCount, error) {
+	var rows []projectCount
+	err := db.Model(&models.Task{}).
+		Select("project_id, COUNT(*) AS task_count, SUM(CASE WHEN completed THEN 1 ELSE 0 END) AS completed_count").
+		Where("user_id = ? AND project_id IS NOT NULL", userID).
+		Group("project_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]projectCount, len(rows))
+	for _, row := range rows {
+		counts[row.ProjectID] = row
+	}
+	return counts, nil
+}
+
+// toProjectResponse 将项目模型转换为响应模型
+func toProjectResponse(project models.Project, count projectCount) models.ProjectResponse {
+	return models.ProjectResponse{
+		ID:             project.ID,
+		Name:           project.Name,
+		Description:    project.Description,
+		Color:          project.Color,
+		Archived:       project.Archived,
+		SortOrder:      project.SortOrder,
+		TaskCount:      count.TaskCount,
+		CompletedCount: count.CompletedCount,
+		CreatedAt:      project.CreatedAt,
+		UpdatedAt:      project.UpdatedAt,
+	}
+}
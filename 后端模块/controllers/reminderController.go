@@ -0,0 +1,384 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/config"
+	"taskmanager/models"
+	"taskmanager/notify"
+)
+
+// 每个任务最多设置的提醒数
+const maxTaskReminders = 5
+
+// 提醒最多提前的分钟数
+const maxReminderOffset = 30 * 24 * 60
+
+// 每轮最多处理的到期提醒数，其余的留到下一轮
+const reminderBatchSize = 100
+
+// 投递失败后的重试间隔，按尝试次数翻倍，不超过reminderMaxBackoff
+const (
+	reminderBaseBackoff = time.Minute
+	reminderMaxBackoff  = time.Hour
+)
+
+// 提醒的投递渠道和最大尝试次数，由InitReminders设置
+var (
+	reminderChannels    []notify.Channel
+	reminderMaxAttempts int
+)
+
+// ReminderRequest 设置任务提醒的请求结构
+type ReminderRequest struct {
+	Offsets []int `json:"offsets"` // 在截止日期前多少分钟提醒，为空表示取消全部提醒
+}
+
+// inboxChannel 站内通知渠道，将提醒写入通知表
+type inboxChannel struct{}
+
+// Name 渠道名称
+func (inboxChannel) Name() string {
+	return "inbox"
+}
+
+// Send 写入一条站内通知
+func (inboxChannel) Send(msg notify.Message) error {
+	taskID := msg.TaskID
+	return db.Create(&models.Notification{
+		UserID: msg.UserID,
+		Type:   models.NotificationReminder,
+		TaskID: &taskID,
+		Title:  excerpt(msg.Title, notificationTitleLength),
+		Body:   msg.Body,
+	}).Error
+}
+
+// InitReminders 根据配置启用投递渠道，并启动后台定时发送到期提醒
+// 站内通知始终启用，邮件和Webhook在配置了地址时启用
+func InitReminders(cfg config.ReminderConfig) {
+	reminderMaxAttempts = cfg.MaxAttempts
+	if reminderMaxAttempts == 0 {
+		reminderMaxAttempts = 1
+	}
+	reminderChannels = []notify.Channel{inboxChannel{}}
+	if cfg.SMTPAddr != "" {
+		reminderChannels = append(reminderChannels, &notify.Email{
+			Addr:     cfg.SMTPAddr,
+			From:     cfg.SMTPFrom,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+		})
+	}
+	if cfg.WebhookURL != "" {
+		reminderChannels = append(reminderChannels, &notify.Webhook{URL: cfg.WebhookURL})
+	}
+
+	names := make([]string, len(reminderChannels))
+	for i, channel := range reminderChannels {
+		names[i] = channel.Name()
+	}
+	log.Printf("到期提醒已启用，渠道: %v", names)
+
+	go func() {
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+		for {
+			sendDueReminders(time.Now())
+			<-ticker.C
+		}
+	}()
+}
+
+// GetTaskReminders 获取任务的提醒
+func GetTaskReminders(c *gin.Context) {
+	task, ok := findTask(c, accessView)
+	if !ok {
+		return
+	}
+	respondTaskReminders(c, task.ID)
+}
+
+// SetTaskReminders 整体替换任务的提醒，需要编辑权限
+func SetTaskReminders(c *gin.Context) {
+	task, ok := findTask(c, accessEdit)
+	if !ok {
+		return
+	}
+
+	var reminderReq ReminderRequest
+	if err := c.ShouldBindJSON(&reminderReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	offsets := make(map[int]bool, len(reminderReq.Offsets))
+	for _, offset := range reminderReq.Offsets {
+		if offset < 0 || offset > maxReminderOffset {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("提醒时间应为截止日期前0-%d分钟", maxReminderOffset)})
+			return
+		}
+		offsets[offset] = true
+	}
+	if len(offsets) > maxTaskReminders {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("每个任务最多设置%d个提醒", maxTaskReminders)})
+		return
+	}
+
+	// 已存在的提醒保留发送状态，只增删有变化的提醒
+	var existing []models.TaskReminder
+	if err := db.Where("task_id = ?", task.ID).Find(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "设置提醒失败"})
+		return
+	}
+	tx := db.Begin()
+	var removed []uint
+	for _, reminder := range existing {
+		if offsets[reminder.OffsetMinutes] {
+			delete(offsets, reminder.OffsetMinutes)
+		} else {
+			removed = append(removed, reminder.ID)
+		}
+	}
+	if err := deleteReminders(tx, removed); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "设置提醒失败"})
+		return
+	}
+	for offset := range offsets {
+		reminder := newReminder(task.ID, offset, task.DueDate)
+		if err := tx.Create(&reminder).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "设置提醒失败"})
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "设置提醒失败"})
+		return
+	}
+
+	respondTaskReminders(c, task.ID)
+}
+
+// respondTaskReminders 返回任务的全部提醒，按触发时间先后排列
+func respondTaskReminders(c *gin.Context, taskID uint) {
+	var reminders []models.TaskReminder
+	if err := db.Where("task_id = ?", taskID).Order("offset_minutes DESC").Find(&reminders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取提醒失败"})
+		return
+	}
+
+	response := make([]models.TaskReminderResponse, len(reminders))
+	for i, reminder := range reminders {
+		response[i] = models.TaskReminderResponse{
+			Offset:   reminder.OffsetMinutes,
+			RemindAt: reminder.RemindAt,
+			SentAt:   reminder.SentAt,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// remindAt 计算提醒的触发时间，任务没有截止日期时为空
+func remindAt(dueDate *time.Time, offset int) *time.Time {
+	if dueDate == nil {
+		return nil
+	}
+	at := dueDate.Add(-time.Duration(offset) * time.Minute)
+	return &at
+}
+
+// newReminder 创建提醒模型，设置时触发时间已经过去的提醒不再发送，直接标记为已发送
+func newReminder(taskID uint, offset int, dueDate *time.Time) models.TaskReminder {
+	reminder := models.TaskReminder{TaskID: taskID, OffsetMinutes: offset, RemindAt: remindAt(dueDate, offset)}
+	if now := time.Now(); reminder.RemindAt != nil && !reminder.RemindAt.After(now) {
+		reminder.SentAt = &now
+	}
+	return reminder
+}
+
+// rescheduleReminders 任务截止日期变化后重新计算提醒时间，已发送的提醒会按新的时间再次发送
+func rescheduleReminders(tx *gorm.DB, task models.Task) error {
+	var reminders []models.TaskReminder
+	if err := tx.Where("task_id = ?", task.ID).Find(&reminders).Error; err != nil {
+		return err
+	}
+	ids := make([]uint, len(reminders))
+	for i, reminder := range reminders {
+		ids[i] = reminder.ID
+		updated := newReminder(task.ID, reminder.OffsetMinutes, task.DueDate)
+		if err := tx.Model(&reminder).Updates(map[string]interface{}{
+			"remind_at": updated.RemindAt,
+			"sent_at":   updated.SentAt,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Where("reminder_id IN (?)", ids).Delete(&models.ReminderDelivery{}).Error
+}
+
+// copyTaskReminders 按新任务的截止日期复制提醒，用于生成下一次重复任务
+func copyTaskReminders(tx *gorm.DB, fromID uint, to models.Task) error {
+	var reminders []models.TaskReminder
+	if err := tx.Where("task_id = ?", fromID).Find(&reminders).Error; err != nil {
+		return err
+	}
+	for _, reminder := range reminders {
+		next := newReminder(to.ID, reminder.OffsetMinutes, to.DueDate)
+		if err := tx.Create(&next).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteReminders 删除提醒及其投递记录
+func deleteReminders(tx *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Where("reminder_id IN (?)", ids).Delete(&models.ReminderDelivery{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id IN (?)", ids).Delete(&models.TaskReminder{}).Error
+}
+
+// sendDueReminders 发送已到触发时间的提醒
+// 每个提醒发给任务创建者和负责人，每个渠道单独记录投递状态并在失败后退避重试，
+// 全部投递成功或放弃后才标记为已发送；已完成的任务直接标记为已发送
+// 未结束的投递都在退避中的提醒本轮跳过，不占用批次，避免反复失败的提醒挤掉新到期的提醒
+func sendDueReminders(now time.Time) {
+	var reminders []models.TaskReminder
+	if err := db.Table("task_reminders").
+		Select("task_reminders.*").
+		Joins("JOIN tasks ON tasks.id = task_reminders.task_id AND tasks.deleted_at IS NULL").
+		Where("task_reminders.sent_at IS NULL AND task_reminders.remind_at <= ?", now).
+		Where("NOT EXISTS (SELECT 1 FROM reminder_deliveries WHERE reminder_deliveries.reminder_id = task_reminders.id"+
+			" AND reminder_deliveries.delivered_at IS NULL AND reminder_deliveries.attempts < ? AND reminder_deliveries.next_attempt_at > ?)"+
+			" OR EXISTS (SELECT 1 FROM reminder_deliveries WHERE reminder_deliveries.reminder_id = task_reminders.id"+
+			" AND reminder_deliveries.delivered_at IS NULL AND reminder_deliveries.attempts < ?"+
+			" AND (reminder_deliveries.next_attempt_at IS NULL OR reminder_deliveries.next_attempt_at <= ?))",
+			reminderMaxAttempts, now, reminderMaxAttempts, now).
+		Order("task_reminders.remind_at ASC").
+		Limit(reminderBatchSize).
+		Scan(&reminders).Error; err != nil {
+		log.Printf("查找到期提醒失败: %v", err)
+		return
+	}
+
+	for _, reminder := range reminders {
+		var task models.Task
+		if err := db.First(&task, reminder.TaskID).Error; err != nil {
+			log.Printf("加载提醒 %d 的任务失败: %v", reminder.ID, err)
+			continue
+		}
+
+		done := true
+		if !task.Completed && task.DueDate != nil {
+			recipients, err := reminderRecipients(task)
+			if err != nil {
+				log.Printf("加载提醒 %d 的接收者失败: %v", reminder.ID, err)
+				continue
+			}
+			msg := reminderMessage(task, now)
+			for _, user := range recipients {
+				msg.UserID, msg.Username, msg.Email = user.ID, user.Username, user.Email
+				for _, channel := range reminderChannels {
+					if !deliverReminder(reminder, channel, msg, now) {
+						done = false
+					}
+				}
+			}
+		}
+		if done {
+			if err := db.Model(&reminder).Update("sent_at", now).Error; err != nil {
+				log.Printf("标记提醒 %d 已发送失败: %v", reminder.ID, err)
+			}
+		}
+	}
+}
+
+// deliverReminder 通过一个渠道将提醒发给一个用户，返回该投递是否已结束（成功或放弃）
+func deliverReminder(reminder models.TaskReminder, channel notify.Channel, msg notify.Message, now time.Time) bool {
+	var delivery models.ReminderDelivery
+	if err := db.Where(models.ReminderDelivery{ReminderID: reminder.ID, UserID: msg.UserID, Channel: channel.Name()}).
+		FirstOrCreate(&delivery).Error; err != nil {
+		log.Printf("记录提醒 %d 的投递状态失败: %v", reminder.ID, err)
+		return false
+	}
+	if delivery.DeliveredAt != nil || delivery.Attempts >= reminderMaxAttempts {
+		return true
+	}
+	if delivery.NextAttemptAt != nil && delivery.NextAttemptAt.After(now) {
+		return false
+	}
+
+	err := channel.Send(msg)
+	if err != nil && err != notify.ErrNoAddress {
+		log.Printf("通过%s发送提醒 %d 给用户 %d 失败（第%d次）: %v", channel.Name(), reminder.ID, msg.UserID, delivery.Attempts+1, err)
+	}
+	updates, finished := reminderAttemptUpdates(delivery, err, now)
+	if err := db.Model(&delivery).Updates(updates).Error; err != nil {
+		log.Printf("记录提醒 %d 的投递状态失败: %v", reminder.ID, err)
+		return false
+	}
+	return finished
+}
+
+// reminderAttemptUpdates 根据一次发送的结果计算投递需要更新的字段，并返回投递是否已结束
+// 接收者没有地址时视为成功；失败后按尝试次数翻倍退避，达到最大尝试次数后放弃
+func reminderAttemptUpdates(delivery models.ReminderDelivery, sendErr error, now time.Time) (map[string]interface{}, bool) {
+	attempts := delivery.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts": attempts,
+	}
+	if sendErr == nil || sendErr == notify.ErrNoAddress {
+		updates["delivered_at"] = now
+		return updates, true
+	}
+
+	backoff := reminderBaseBackoff << uint(attempts-1)
+	if backoff > reminderMaxBackoff || backoff <= 0 {
+		backoff = reminderMaxBackoff
+	}
+	updates["last_error"] = excerpt(sendErr.Error(), 255)
+	updates["next_attempt_at"] = now.Add(backoff)
+	return updates, attempts >= reminderMaxAttempts
+}
+
+// reminderRecipients 提醒的接收者：仍是任务所在工作区成员的任务创建者和负责人
+func reminderRecipients(task models.Task) ([]models.User, error) {
+	var users []models.User
+	assignees := db.Model(&models.TaskAssignee{}).Select("user_id").Where("task_id = ?", task.ID).SubQuery()
+	members := db.Model(&models.Membership{}).Select("user_id").Where("workspace_id = ?", task.WorkspaceID).SubQuery()
+	err := db.Where("id = ? OR id IN ?", task.UserID, assignees).
+		Where("id IN ?", members).
+		Order("id ASC").Find(&users).Error
+	return users, err
+}
+
+// reminderMessage 生成提醒的标题和正文
+func reminderMessage(task models.Task, now time.Time) notify.Message {
+	due := task.DueDate.Local().Format("2006-01-02 15:04")
+	msg := notify.Message{
+		Event:   "task.reminder",
+		TaskID:  task.ID,
+		Title:   "任务即将到期：" + task.Title,
+		Body:    fmt.Sprintf("任务「%s」将于 %s 到期。", task.Title, due),
+		DueDate: *task.DueDate,
+	}
+	if !task.DueDate.After(now) {
+		msg.Title = "任务已到期：" + task.Title
+		msg.Body = fmt.Sprintf("任务「%s」已于 %s 到期。", task.Title, due)
+	}
+	return msg
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"taskmanager/models"
+	"taskmanager/notify"
+)
+
+func TestReminderAttemptUpdates(t *testing.T) {
+	saved := reminderMaxAttempts
+	reminderMaxAttempts = 5
+	defer func() { reminderMaxAttempts = saved }()
+
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	sendErr := errors.New("连接被拒绝")
+	tests := []struct {
+		name         string
+		attempts     int
+		err          error
+		wantFinished bool
+		wantBackoff  time.Duration // 为0表示不再重试
+	}{
+		{"首次成功", 0, nil, true, 0},
+		{"没有地址视为成功", 0, notify.ErrNoAddress, true, 0},
+		{"重试后成功", 3, nil, true, 0},
+		{"首次失败", 0, sendErr, false, time.Minute},
+		{"第二次失败翻倍", 1, sendErr, false, 2 * time.Minute},
+		{"第四次失败", 3, sendErr, false, 8 * time.Minute},
+		{"达到最大次数后放弃", 4, sendErr, true, 16 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates, finished := reminderAttemptUpdates(models.ReminderDelivery{Attempts: tt.attempts}, tt.err, now)
+			if finished != tt.wantFinished {
+				t.Errorf("finished = %v, 期望 %v", finished, tt.wantFinished)
+			}
+			if updates["attempts"] != tt.attempts+1 {
+				t.Errorf("attempts = %v, 期望 %d", updates["attempts"], tt.attempts+1)
+			}
+			if tt.err == nil || tt.err == notify.ErrNoAddress {
+				if updates["delivered_at"] != now {
+					t.Errorf("delivered_at = %v, 期望 %v", updates["delivered_at"], now)
+				}
+				if _, ok := updates["next_attempt_at"]; ok {
+					t.Error("成功后不应设置next_attempt_at")
+				}
+				return
+			}
+			if _, ok := updates["delivered_at"]; ok {
+				t.Error("失败后不应设置delivered_at")
+			}
+			if updates["next_attempt_at"] != now.Add(tt.wantBackoff) {
+				t.Errorf("next_attempt_at = %v, 期望 %v", updates["next_attempt_at"], now.Add(tt.wantBackoff))
+			}
+			if updates["last_error"] != sendErr.Error() {
+				t.Errorf("last_error = %v", updates["last_error"])
+			}
+		})
+	}
+}
+
+func TestReminderAttemptUpdatesMaxBackoff(t *testing.T) {
+	saved := reminderMaxAttempts
+	reminderMaxAttempts = 100
+	defer func() { reminderMaxAttempts = saved }()
+
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	for _, attempts := range []int{6, 7, 20, 70} {
+		updates, _ := reminderAttemptUpdates(models.ReminderDelivery{Attempts: attempts}, errors.New("超时"), now)
+		if updates["next_attempt_at"] != now.Add(reminderMaxBackoff) {
+			t.Errorf("第%d次失败: next_attempt_at = %v, 期望不超过 %v", attempts+1, updates["next_attempt_at"], reminderMaxBackoff)
+		}
+	}
+}
+
+func TestReminderAttemptUpdatesTruncatesError(t *testing.T) {
+	now := time.Now()
+	long := strings.Repeat("错", 300)
+	updates, _ := reminderAttemptUpdates(models.ReminderDelivery{}, errors.New(long), now)
+	lastError := updates["last_error"].(string)
+	if !utf8.ValidString(lastError) {
+		t.Fatal("截断后的错误信息不是有效的UTF-8")
+	}
+	if n := utf8.RuneCountInString(lastError); n != 255 {
+		t.Errorf("错误信息长度 = %d, 期望 255", n)
+	}
+}
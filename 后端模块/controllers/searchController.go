@@ -0,0 +1,350 @@
+package controllers
+
+import (
+	"html"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/models"
+)
+
+// 搜索相关限制
+const (
+	taskFullTextIndex = "idx_task_fulltext"
+	maxSearchLength   = 100 // 关键词最大长度（字符）
+	maxSearchTerms    = 10  // 最多使用的关键词个数
+	snippetRadius     = 30  // 描述片段在匹配位置前后保留的字符数
+	ngramTokenSize    = 2   // MySQL ngram分词长度，短于它的关键词无法走全文索引
+)
+
+// fullTextSearch 是否可以使用MySQL全文索引，由InitTaskSearch设置
+var fullTextSearch bool
+
+// searchHit 搜索命中的任务ID和相关度
+type searchHit struct {
+	ID    uint
+	Score float64
+}
+
+// InitTaskSearch 为任务标题和描述创建全文索引，仅MySQL可用
+// 使用ngram分词以支持中文；创建失败时记录日志，搜索退回到LIKE匹配
+func InitTaskSearch() {
+	if db.Dialect().GetName() != "mysql" {
+		return
+	}
+	if !db.Dialect().HasIndex("tasks", taskFullTextIndex) {
+		sql := "CREATE FULLTEXT INDEX " + taskFullTextIndex + " ON tasks (title, description) WITH PARSER ngram"
+		if err := db.Exec(sql).Error; err != nil {
+			log.Printf("创建全文索引失败，任务搜索将使用LIKE匹配: %v", err)
+			return
+		}
+	}
+	fullTextSearch = true
+}
+
+// SearchTasks 按关键词搜索任务标题和描述
+// 多个关键词用空格分隔，需全部匹配；支持与任务列表相同的筛选参数
+func SearchTasks(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 解析关键词
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "搜索关键词不能为空"})
+		return
+	}
+	if utf8.RuneCountInString(q) > maxSearchLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "搜索关键词过长"})
+		return
+	}
+	terms := splitSearchTerms(q)
+
+	// 解析分页参数
+	pageSize, err := parsePageSize(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := parsePageNumber(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 应用筛选条件，范围包括自己创建、共享给自己和自己负责的任务
+	scope := db.Model(&models.Task{}).
+		Where("workspace_id = ?", currentWorkspace(c)).
+		Where("user_id = ? OR id IN ? OR id IN ?", userID, accessibleTaskIDs(userID, "shared"), accessibleTaskIDs(userID, "assigned"))
+	query, ok := filterTaskProject(c, scope)
+	if !ok {
+		return
+	}
+	if query, ok = filterTasks(c, query); !ok {
+		return
+	}
+
+	// 查询当前页命中的任务
+	var hits []searchHit
+	var total int
+	if canUseFullText(terms) {
+		hits, total, err = searchFullText(query, terms, page, pageSize)
+	} else {
+		hits, total, err = searchLike(query, terms, page, pageSize)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索任务失败"})
+		return
+	}
+
+	// 加载任务详情，保持相关度顺序
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	var tasks []models.Task
+	if len(ids) > 0 {
+		if err := db.Where("id IN (?)", ids).Preload("Tags").Preload("Attachments").Find(&tasks).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索任务失败"})
+			return
+		}
+	}
+	byID := make(map[uint]models.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+
+	// 加载当前页任务的层级，用于计算子任务统计和进度
+	tree, err := loadTaskTree(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索任务失败"})
+		return
+	}
+
+	// 结果可能包含他人共享或指派的任务，返回当前用户的权限
+	levels, err := taskAccessLevels(userID.(uint), tasks)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索任务失败"})
+		return
+	}
+
+	result := models.TaskSearchResponse{
+		Items:    make([]models.TaskSearchResult, 0, len(hits)),
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	for _, hit := range hits {
+		task, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		item := models.TaskSearchResult{
+			TaskResponse: toTaskResponse(task),
+			Score:        hit.Score,
+			Highlights: models.TaskHighlights{
+				Title:       highlightText(task.Title, terms),
+				Description: highlightSnippet(task.Description, terms),
+			},
+		}
+		tree.fill(&item.TaskResponse)
+		item.Permission = levels[task.ID].String()
+		result.Items = append(result.Items, item)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// splitSearchTerms 按空白拆分关键词，转为小写并去重
+func splitSearchTerms(q string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, term := range strings.Fields(strings.ToLower(q)) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// canUseFullText 判断本次搜索能否使用全文索引
+func canUseFullText(terms []string) bool {
+	if !fullTextSearch {
+		return false
+	}
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < ngramTokenSize {
+			return false
+		}
+	}
+	return true
+}
+
+// searchFullText 使用MySQL全文索引搜索，相关度由MATCH AGAINST计算
+func searchFullText(query *gorm.DB, terms []string, page, pageSize int) ([]searchHit, int, error) {
+	// 布尔模式下每个关键词作为必须出现的短语
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = `+"` + strings.Replace(term, `"`, "", -1) + `"`
+	}
+	against := strings.Join(parts, " ")
+	match := "MATCH(title, description) AGAINST (? IN BOOLEAN MODE)"
+
+	query = query.Where(match, against)
+	var total int
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var hits []searchHit
+	err := query.Select("id, "+match+" AS score", against).
+		Order("score DESC").Order("id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Scan(&hits).Error
+	return hits, total, err
+}
+
+// searchLike 使用LIKE匹配搜索，用于非MySQL数据库或关键词过短的情况
+// 相关度按关键词出现次数计算，标题中的匹配权重更高
+func searchLike(query *gorm.DB, terms []string, page, pageSize int) ([]searchHit, int, error) {
+	for _, term := range terms {
+		pattern := "%" + escapeLike(term) + "%"
+		query = query.Where("(title LIKE ? ESCAPE '!' OR description LIKE ? ESCAPE '!')", pattern, pattern)
+	}
+
+	var rows []struct {
+		ID          uint
+		Title       string
+		Description string
+	}
+	if err := query.Select("id, title, description").Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]searchHit, len(rows))
+	for i, row := range rows {
+		score := 0
+		for _, term := range terms {
+			score += 3*strings.Count(strings.ToLower(row.Title), term) + strings.Count(strings.ToLower(row.Description), term)
+		}
+		hits[i] = searchHit{ID: row.ID, Score: float64(score)}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID > hits[j].ID
+	})
+
+	total := len(hits)
+	start := (page - 1) * pageSize
+	if start >= total {
+		return nil, total, nil
+	}
+	end := start + pageSize
+	if end > total {
+		end = total
+	}
+	return hits[start:end], total, nil
+}
+
+// escapeLike 转义LIKE中的通配符，转义字符为!
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
+
+// highlightText 转义文本并用<mark>标签包裹全部匹配部分
+func highlightText(text string, terms []string) string {
+	runes := []rune(text)
+	return renderHighlight(runes, matchedRunes(runes, terms), 0, len(runes))
+}
+
+// highlightSnippet 截取第一个匹配位置附近的片段并高亮
+// 没有匹配时返回开头的一段
+func highlightSnippet(text string, terms []string) string {
+	runes := []rune(text)
+	marked := matchedRunes(runes, terms)
+
+	first := -1
+	for i, m := range marked {
+		if m {
+			first = i
+			break
+		}
+	}
+
+	start, end := 0, 2*snippetRadius
+	if first >= 0 {
+		start, end = first-snippetRadius, first+snippetRadius
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	snippet := renderHighlight(runes, marked, start, end)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// matchedRunes 标记文本中与任一关键词匹配（不区分大小写）的字符
+func matchedRunes(runes []rune, terms []string) []bool {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(runes))
+	for _, term := range terms {
+		t := []rune(term)
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == term {
+				for j := i; j < i+len(t); j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+	return marked
+}
+
+// renderHighlight 输出[start, end)范围内转义后的文本，连续的匹配字符合并为一个<mark>
+func renderHighlight(runes []rune, marked []bool, start, end int) string {
+	var b strings.Builder
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			segment = "<mark>" + segment + "</mark>"
+		}
+		b.WriteString(segment)
+		i = j
+	}
+	return b.String()
+}
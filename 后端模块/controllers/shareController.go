@@ -0,0 +1,406 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/models"
+)
+
+// taskAccess 用户对任务的权限，数值越大权限越高
+type taskAccess int
+
+// 任务权限级别
+const (
+	accessNone  taskAccess = iota // 无权访问
+	accessView                    // 通过view共享查看任务
+	accessEdit                    // 通过edit共享或作为负责人修改任务
+	accessOwner                   // 任务创建者，可以删除、移动和共享任务
+)
+
+// String 返回响应中使用的权限名称
+func (a taskAccess) String() string {
+	switch a {
+	case accessOwner:
+		return "owner"
+	case accessEdit:
+		return models.SharePermissionEdit
+	case accessView:
+		return models.SharePermissionView
+	}
+	return ""
+}
+
+// AssigneeRequest 添加或移除任务负责人的请求结构
+type AssigneeRequest struct {
+	Username string `json:"username" binding:"required"` // 负责人的用户名
+}
+
+// ShareRequest 共享任务或取消共享的请求结构
+type ShareRequest struct {
+	Username   string `json:"username" binding:"required"` // 被共享的用户名
+	Permission string `json:"permission"`                  // view或edit，共享时必填，取消共享时忽略
+}
+
+// taskAccessLevels 计算用户对一组任务的权限
+// 创建者拥有全部权限，负责人可以编辑，其他用户取决于共享记录
+func taskAccessLevels(userID uint, tasks []models.Task) (map[uint]taskAccess, error) {
+	levels := make(map[uint]taskAccess, len(tasks))
+	var others []uint
+	for _, task := range tasks {
+		if task.UserID == userID {
+			levels[task.ID] = accessOwner
+		} else {
+			others = append(others, task.ID)
+		}
+	}
+	if len(others) == 0 {
+		return levels, nil
+	}
+
+	var shares []models.TaskShare
+	if err := db.Where("user_id = ? AND task_id IN (?)", userID, others).Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	for _, share := range shares {
+		levels[share.TaskID] = accessView
+		if share.Permission == models.SharePermissionEdit {
+			levels[share.TaskID] = accessEdit
+		}
+	}
+
+	var assigned []uint
+	if err := db.Model(&models.TaskAssignee{}).Where("user_id = ? AND task_id IN (?)", userID, others).
+		Pluck("task_id", &assigned).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range assigned {
+		levels[id] = accessEdit
+	}
+	return levels, nil
+}
+
+// accessibleTaskIDs 返回共享给用户或用户负责的任务ID子查询，view取值为shared或assigned
+func accessibleTaskIDs(userID interface{}, view string) interface{} {
+	if view == "assigned" {
+		return db.Model(&models.TaskAssignee{}).Select("task_id").Where("user_id = ?", userID).SubQuery()
+	}
+	return db.Model(&models.TaskShare{}).Select("task_id").Where("user_id = ?", userID).SubQuery()
+}
+
+// fillPermissions 填充响应中当前用户对任务的权限
+func fillPermissions(userID uint, tasks []models.Task, responses []models.TaskResponse) error {
+	levels, err := taskAccessLevels(userID, tasks)
+	if err != nil {
+		return err
+	}
+	for i := range responses {
+		responses[i].Permission = levels[responses[i].ID].String()
+	}
+	return nil
+}
+
+// GetTaskShares 获取任务的共享记录，只有创建者可以查看
+func GetTaskShares(c *gin.Context) {
+	task, ok := findTask(c, accessOwner)
+	if !ok {
+		return
+	}
+
+	shares, err := loadTaskShares(task.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取共享记录失败"})
+		return
+	}
+	c.JSON(http.StatusOK, shares)
+}
+
+// ShareTask 将任务共享给其他用户，已共享时更新权限
+func ShareTask(c *gin.Context) {
+	task, ok := findTask(c, accessOwner)
+	if !ok {
+		return
+	}
+
+	var shareReq ShareRequest
+	if err := c.ShouldBindJSON(&shareReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if shareReq.Permission != models.SharePermissionView && shareReq.Permission != models.SharePermissionEdit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的共享权限，可选值为: view, edit"})
+		return
+	}
+	user, ok := findUserByName(c, shareReq.Username)
+	if !ok {
+		return
+	}
+	if user.ID == task.UserID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能将任务共享给自己"})
+		return
+	}
+	if !isWorkspaceMember(task.WorkspaceID, user.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户不是工作区成员"})
+		return
+	}
+
+	var share models.TaskShare
+	if db.Where("task_id = ? AND user_id = ?", task.ID, user.ID).First(&share).RecordNotFound() {
+		share = models.TaskShare{TaskID: task.ID, UserID: user.ID, Permission: shareReq.Permission}
+		if err := db.Create(&share).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "共享任务失败"})
+			return
+		}
+	} else if share.Permission != shareReq.Permission {
+		if err := db.Model(&share).Update("permission", shareReq.Permission).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "共享任务失败"})
+			return
+		}
+	}
+	publishTaskEvent(streamTaskUpdated, task)
+
+	shares, err := loadTaskShares(task.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取共享记录失败"})
+		return
+	}
+	c.JSON(http.StatusOK, shares)
+}
+
+// UnshareTask 取消任务对某个用户的共享，不影响该用户作为负责人的权限
+func UnshareTask(c *gin.Context) {
+	task, ok := findTask(c, accessOwner)
+	if !ok {
+		return
+	}
+
+	var shareReq ShareRequest
+	if err := c.ShouldBindJSON(&shareReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	user, ok := findUserByName(c, shareReq.Username)
+	if !ok {
+		return
+	}
+
+	result := db.Where("task_id = ? AND user_id = ?", task.ID, user.ID).Delete(&models.TaskShare{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "取消共享失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务未共享给该用户"})
+		return
+	}
+	// 被取消共享的用户也需要收到推送，以便从列表中移除该任务
+	publishTaskEvent(streamTaskUpdated, task, user.ID)
+
+	shares, err := loadTaskShares(task.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取共享记录失败"})
+		return
+	}
+	c.JSON(http.StatusOK, shares)
+}
+
+// AddTaskAssignee 添加任务负责人，负责人获得任务的编辑权限并收到通知
+func AddTaskAssignee(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	task, ok := findTask(c, accessEdit)
+	if !ok {
+		return
+	}
+
+	var assigneeReq AssigneeRequest
+	if err := c.ShouldBindJSON(&assigneeReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	user, ok := findUserByName(c, assigneeReq.Username)
+	if !ok {
+		return
+	}
+	if !isWorkspaceMember(task.WorkspaceID, user.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户不是工作区成员"})
+		return
+	}
+	if isAssigned(task.ID, user.ID) {
+		respondTask(c, task)
+		return
+	}
+
+	tx := db.Begin()
+	if err := changeAssignees(tx, &task, userID.(uint), func(tx *gorm.DB) error {
+		return tx.Create(&models.TaskAssignee{TaskID: task.ID, UserID: user.ID}).Error
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加负责人失败"})
+		return
+	}
+	if err := notifyAssigned(tx, task, userID.(uint), user.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加负责人失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加负责人失败"})
+		return
+	}
+	publishTaskEvent(streamTaskUpdated, task)
+
+	respondTask(c, task)
+}
+
+// RemoveTaskAssignee 移除任务负责人
+func RemoveTaskAssignee(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	task, ok := findTask(c, accessEdit)
+	if !ok {
+		return
+	}
+
+	var assigneeReq AssigneeRequest
+	if err := c.ShouldBindJSON(&assigneeReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	user, ok := findUserByName(c, assigneeReq.Username)
+	if !ok {
+		return
+	}
+	if !isAssigned(task.ID, user.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "该用户不是任务的负责人"})
+		return
+	}
+
+	tx := db.Begin()
+	if err := changeAssignees(tx, &task, userID.(uint), func(tx *gorm.DB) error {
+		return tx.Where("task_id = ? AND user_id = ?", task.ID, user.ID).Delete(&models.TaskAssignee{}).Error
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除负责人失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除负责人失败"})
+		return
+	}
+	publishTaskEvent(streamTaskUpdated, task, user.ID)
+
+	respondTask(c, task)
+}
+
+// copyTaskAccess 将任务的负责人和共享记录复制到另一个任务，用于生成下一次重复任务
+func copyTaskAccess(tx *gorm.DB, fromID, toID uint) error {
+	var assignees []models.TaskAssignee
+	if err := tx.Where("task_id = ?", fromID).Order("id ASC").Find(&assignees).Error; err != nil {
+		return err
+	}
+	for _, assignee := range assignees {
+		if err := tx.Create(&models.TaskAssignee{TaskID: toID, UserID: assignee.UserID}).Error; err != nil {
+			return err
+		}
+	}
+
+	var shares []models.TaskShare
+	if err := tx.Where("task_id = ?", fromID).Order("id ASC").Find(&shares).Error; err != nil {
+		return err
+	}
+	for _, share := range shares {
+		if err := tx.Create(&models.TaskShare{TaskID: toID, UserID: share.UserID, Permission: share.Permission}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findUserByName 按用户名查找用户，失败时已写入错误响应
+func findUserByName(c *gin.Context, username string) (models.User, bool) {
+	var user models.User
+	if db.Where("username = ?", username).First(&user).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return user, false
+	}
+	return user, true
+}
+
+// isAssigned 判断用户是否为任务的负责人
+func isAssigned(taskID, userID uint) bool {
+	var count int
+	db.Model(&models.TaskAssignee{}).Where("task_id = ? AND user_id = ?", taskID, userID).Count(&count)
+	return count > 0
+}
+
+// changeAssignees 在事务中修改任务负责人，更新任务版本号并写入变更记录
+func changeAssignees(tx *gorm.DB, task *models.Task, actorID uint, change func(tx *gorm.DB) error) error {
+	before, err := assigneeNames(tx, task.ID)
+	if err != nil {
+		return err
+	}
+	if err := change(tx); err != nil {
+		return err
+	}
+	after, err := assigneeNames(tx, task.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(task).Updates(map[string]interface{}{"version": bumpVersion}).Error; err != nil {
+		return err
+	}
+	task.Version++
+	changes := []models.FieldChange{{Field: "assignees", Old: before, New: after}}
+	return recordTaskEvent(tx, task.ID, actorID, models.TaskEventUpdated, changes)
+}
+
+// assigneeNames 返回任务负责人的用户名，按添加顺序排列
+func assigneeNames(tx *gorm.DB, taskID uint) ([]string, error) {
+	names := []string{}
+	err := tx.Table("users").
+		Joins("JOIN task_assignees ON task_assignees.user_id = users.id").
+		Where("task_assignees.task_id = ?", taskID).
+		Order("task_assignees.id ASC").
+		Pluck("users.username", &names).Error
+	return names, err
+}
+
+// loadTaskShares 加载任务的共享记录，按共享时间排列
+func loadTaskShares(taskID uint) ([]models.TaskShareResponse, error) {
+	var rows []struct {
+		models.TaskShare
+		Username string
+	}
+	if err := db.Table("task_shares").
+		Select("task_shares.*, users.username").
+		Joins("JOIN users ON users.id = task_shares.user_id").
+		Where("task_shares.task_id = ?", taskID).
+		Order("task_shares.id ASC").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	shares := make([]models.TaskShareResponse, len(rows))
+	for i, row := range rows {
+		shares[i] = models.TaskShareResponse{
+			User:       models.UserSummary{ID: row.UserID, Username: row.Username},
+			Permission: row.Permission,
+			CreatedAt:  row.CreatedAt,
+		}
+	}
+	return shares, nil
+}
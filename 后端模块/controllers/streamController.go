@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/config"
+	"taskmanager/models"
+	"taskmanager/realtime"
+)
+
+// 推送的事件类型
+const (
+	streamTaskCreated  = "task.created"
+	streamTaskUpdated  = "task.updated"
+	streamTaskDeleted  = "task.deleted"
+	streamFileUploaded = "file.uploaded"
+	streamFileDeleted  = "file.deleted"
+)
+
+// 实时推送的事件中心和心跳间隔，由InitStream设置
+var (
+	streamHub       = realtime.NewHub(1)
+	streamHeartbeat = 25 * time.Second
+)
+
+// InitStream 根据配置创建事件中心
+func InitStream(cfg config.StreamConfig) {
+	streamHub = realtime.NewHub(cfg.Backlog)
+	streamHeartbeat = cfg.Heartbeat
+}
+
+// StreamEvents 以Server-Sent Events推送当前工作区中与用户相关的任务和文件变更
+// 请求头Last-Event-ID（或查询参数lastEventId）为上次收到的事件ID时补发错过的事件，
+// 访问令牌到期时服务端断开连接，客户端刷新令牌后重连
+func StreamEvents(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+	sub, missed, latestID, resumed := streamHub.Subscribe(userID.(uint), currentWorkspace(c), lastEventID)
+	defer streamHub.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 禁止Nginx缓冲
+	c.Status(http.StatusOK)
+
+	// ready事件告知客户端是否续传成功，未续传时客户端需要重新加载数据，并从latestID开始记录
+	ready := realtime.Event{Type: "ready", Data: gin.H{"resumed": resumed}}
+	if !resumed {
+		ready.ID = latestID
+	}
+	if !writeStreamEvent(c, ready) {
+		return
+	}
+	for _, event := range missed {
+		if !writeStreamEvent(c, event) {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	var expired <-chan time.Time
+	if expiresAt, ok := c.Get("tokenExpiresAt"); ok {
+		timer := time.NewTimer(time.Until(expiresAt.(time.Time)))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-expired:
+			return
+		case event, ok := <-sub.C:
+			if !ok || !writeStreamEvent(c, event) {
+				return
+			}
+		case <-heartbeat.C:
+			// 注释行不会触发客户端的事件处理，只用于保持连接
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeStreamEvent 按SSE格式写出一个事件，返回连接是否仍可写
+func writeStreamEvent(c *gin.Context, event realtime.Event) bool {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		log.Printf("序列化推送事件失败: %v", err)
+		return true
+	}
+	if event.ID != "" {
+		if _, err := fmt.Fprintf(c.Writer, "id: %s\n", event.ID); err != nil {
+			return false
+		}
+	}
+	if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+		return false
+	}
+	c.Writer.Flush()
+	return true
+}
+
+// publishTaskEvent 推送任务变更给能看到该任务的用户：创建者、共享用户和负责人
+// extra为额外的接收者，如刚被取消共享、需要从列表中移除该任务的用户
+func publishTaskEvent(eventType string, task models.Task, extra ...uint) {
+	recipients := taskRecipients([]models.Task{task})
+	publishEvent(eventType, task.WorkspaceID, append(recipients[task.ID], extra...), taskEventData(task))
+}
+
+// publishTaskEvents 推送多个任务的相同变更，接收者一次查出
+func publishTaskEvents(eventType string, tasks []models.Task) {
+	recipients := taskRecipients(tasks)
+	for _, task := range tasks {
+		publishEvent(eventType, task.WorkspaceID, recipients[task.ID], taskEventData(task))
+	}
+}
+
+// taskEventData 任务变更事件的内容
+func taskEventData(task models.Task) gin.H {
+	return gin.H{
+		"taskId":    task.ID,
+		"parentId":  task.ParentID,
+		"title":     task.Title,
+		"status":    task.Status,
+		"completed": task.Completed,
+		"version":   task.Version,
+	}
+}
+
+// taskRecipients 返回每个任务的推送对象：创建者、共享用户和负责人
+// 查询失败时只记录日志，仍推送给已查到的用户
+func taskRecipients(tasks []models.Task) map[uint][]uint {
+	recipients := make(map[uint][]uint, len(tasks))
+	ids := make([]uint, len(tasks))
+	for i, task := range tasks {
+		recipients[task.ID] = []uint{task.UserID}
+		ids[i] = task.ID
+	}
+	if len(ids) == 0 {
+		return recipients
+	}
+
+	for _, table := range []string{"task_shares", "task_assignees"} {
+		var rows []struct {
+			TaskID uint
+			UserID uint
+		}
+		if err := db.Table(table).Select("task_id, user_id").Where("task_id IN (?)", ids).
+			Scan(&rows).Error; err != nil {
+			log.Printf("加载任务的推送对象失败: %v", err)
+			continue
+		}
+		for _, row := range rows {
+			recipients[row.TaskID] = append(recipients[row.TaskID], row.UserID)
+		}
+	}
+	return recipients
+}
+
+// publishFileEvent 推送文件变更给文件所有者
+func publishFileEvent(eventType string, file models.File) {
+	publishEvent(eventType, file.WorkspaceID, []uint{file.UserID}, gin.H{
+		"fileId":   file.ID,
+		"fileName": file.OriginalName,
+		"taskId":   file.TaskID,
+	})
+}
+
+// publishEvent 将事件推送给在线的接收者，并交给后台投递到接收者订阅了该事件的Webhook
+func publishEvent(eventType string, workspaceID uint, recipients []uint, data gin.H) {
+	streamHub.Publish(eventType, workspaceID, recipients, data)
+	queueWebhookEvent(webhookEvent{
+		Type:        eventType,
+		WorkspaceID: workspaceID,
+		UserIDs:     recipients,
+		Data:        data,
+		OccurredAt:  time.Now(),
+	})
+}
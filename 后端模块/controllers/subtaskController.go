@@ -0,0 +1,373 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/models"
+)
+
+// taskTree 部分任务的层级结构、依赖关系和负责人，用于计算子任务统计、进度和阻塞状态
+type taskTree struct {
+	parent     map[uint]uint                 // 任务ID -> 父任务ID，顶层任务为0
+	children   map[uint][]uint               // 任务ID -> 直接子任务ID，只对加载了子树的任务完整
+	completed  map[uint]bool                 // 任务ID -> 是否完成
+	blockers   map[uint][]uint               // 任务ID -> 阻塞它的任务ID
+	dependents map[uint][]uint               // 任务ID -> 被它阻塞的任务ID
+	assignees  map[uint][]models.UserSummary // 任务ID -> 负责人
+}
+
+// loadTaskTree 加载给定任务及其全部后代的层级关系、直接依赖关系和负责人
+// 只加载为这些任务计算子任务统计、进度和阻塞状态所需的数据，依赖关系另一端的任务只加载完成状态；
+// 回收站中的任务不参与
+func loadTaskTree(ids []uint) (*taskTree, error) {
+	tree := &taskTree{
+		parent:     make(map[uint]uint, len(ids)),
+		children:   make(map[uint][]uint),
+		completed:  make(map[uint]bool, len(ids)),
+		blockers:   make(map[uint][]uint),
+		dependents: make(map[uint][]uint),
+		assignees:  make(map[uint][]models.UserSummary),
+	}
+	if len(ids) == 0 {
+		return tree, nil
+	}
+
+	// 先加载任务本身，再逐层加载子任务，直到没有更深的子任务
+	var tasks []models.Task
+	if err := db.Select("id, parent_id, completed").Where("id IN (?)", ids).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	var loaded []uint
+	for len(tasks) > 0 {
+		var frontier []uint
+		for _, task := range tasks {
+			if _, seen := tree.parent[task.ID]; seen {
+				continue
+			}
+			var parentID uint
+			if task.ParentID != nil {
+				parentID = *task.ParentID
+			}
+			tree.parent[task.ID] = parentID
+			tree.completed[task.ID] = task.Completed
+			frontier = append(frontier, task.ID)
+		}
+		if len(frontier) == 0 {
+			break
+		}
+		loaded = append(loaded, frontier...)
+		tasks = nil
+		if err := db.Select("id, parent_id, completed").Where("parent_id IN (?)", frontier).Order("id ASC").Find(&tasks).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(loaded) == 0 {
+		return tree, nil
+	}
+	// 已加载任务的子任务都已加载，子任务列表是完整的
+	for _, id := range loaded {
+		if parentID := tree.parent[id]; parentID != 0 {
+			if _, ok := tree.parent[parentID]; ok {
+				tree.children[parentID] = append(tree.children[parentID], id)
+			}
+		}
+	}
+
+	// 加载涉及这些任务的依赖关系，另一端的任务只需要完成状态
+	var dependencies []models.TaskDependency
+	if err := db.Where("task_id IN (?) OR blocker_id IN (?)", loaded, loaded).Order("id ASC").Find(&dependencies).Error; err != nil {
+		return nil, err
+	}
+	var others []uint
+	for _, dependency := range dependencies {
+		for _, id := range []uint{dependency.TaskID, dependency.BlockerID} {
+			if _, ok := tree.parent[id]; !ok {
+				others = append(others, id)
+			}
+		}
+	}
+	if len(others) > 0 {
+		var tasks []models.Task
+		if err := db.Select("id, parent_id, completed").Where("id IN (?)", others).Find(&tasks).Error; err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			var parentID uint
+			if task.ParentID != nil {
+				parentID = *task.ParentID
+			}
+			tree.parent[task.ID] = parentID
+			tree.completed[task.ID] = task.Completed
+		}
+	}
+	for _, dependency := range dependencies {
+		_, taskExists := tree.parent[dependency.TaskID]
+		_, blockerExists := tree.parent[dependency.BlockerID]
+		if taskExists && blockerExists {
+			tree.blockers[dependency.TaskID] = append(tree.blockers[dependency.TaskID], dependency.BlockerID)
+			tree.dependents[dependency.BlockerID] = append(tree.dependents[dependency.BlockerID], dependency.TaskID)
+		}
+	}
+
+	var assignees []struct {
+		TaskID   uint
+		ID       uint
+		Username string
+	}
+	if err := db.Table("task_assignees").
+		Select("task_assignees.task_id, users.id, users.username").
+		Joins("JOIN users ON users.id = task_assignees.user_id").
+		Where("task_assignees.task_id IN (?)", ids).
+		Order("task_assignees.id ASC").
+		Scan(&assignees).Error; err != nil {
+		return nil, err
+	}
+	for _, assignee := range assignees {
+		tree.assignees[assignee.TaskID] = append(tree.assignees[assignee.TaskID], models.UserSummary{ID: assignee.ID, Username: assignee.Username})
+	}
+	return tree, nil
+}
+
+// containsID 判断ID列表中是否包含id
+func containsID(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// taskIDs 返回任务的ID列表
+func taskIDs(tasks []models.Task) []uint {
+	ids := make([]uint, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return ids
+}
+
+// descendants 返回任务的全部后代任务ID（不含自身）
+func (t *taskTree) descendants(id uint) []uint {
+	var result []uint
+	queue := append([]uint(nil), t.children[id]...)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		result = append(result, current)
+		queue = append(queue, t.children[current]...)
+	}
+	return result
+}
+
+// progress 计算任务的完成百分比
+// 有子任务时按后代中叶子任务的完成比例计算，否则取自身完成状态
+func (t *taskTree) progress(id uint) int {
+	var leaves, done int
+	for _, d := range t.descendants(id) {
+		if len(t.children[d]) > 0 {
+			continue
+		}
+		leaves++
+		if t.completed[d] {
+			done++
+		}
+	}
+	if leaves == 0 {
+		if t.completed[id] {
+			return 100
+		}
+		return 0
+	}
+	return done * 100 / leaves
+}
+
+// fill 填充响应中的子任务统计、进度、依赖关系和负责人
+func (t *taskTree) fill(response *models.TaskResponse) {
+	children := t.children[response.ID]
+	response.ChildCount = len(children)
+	response.CompletedChildCount = 0
+	for _, child := range children {
+		if t.completed[child] {
+			response.CompletedChildCount++
+		}
+	}
+	response.Progress = t.progress(response.ID)
+	response.BlockedBy = append([]uint{}, t.blockers[response.ID]...)
+	response.Blocks = append([]uint{}, t.dependents[response.ID]...)
+	response.Blocked = !t.completed[response.ID] && len(t.openBlockers(response.ID)) > 0
+	response.Assignees = append([]models.UserSummary{}, t.assignees[response.ID]...)
+}
+
+// openBlockers 返回阻塞任务的未完成任务ID
+func (t *taskTree) openBlockers(id uint) []uint {
+	var open []uint
+	for _, blocker := range t.blockers[id] {
+		if !t.completed[blocker] {
+			open = append(open, blocker)
+		}
+	}
+	return open
+}
+
+// nestTaskResponses 将任务列表组装为树形结构
+// 父任务不在列表中的任务（例如被筛选掉）作为根节点返回
+func nestTaskResponses(responses []models.TaskResponse) []models.TaskResponse {
+	index := make(map[uint]int, len(responses))
+	for i, response := range responses {
+		index[response.ID] = i
+	}
+
+	childIDs := make(map[uint][]int)
+	var roots []int
+	for i, response := range responses {
+		if response.ParentID != nil {
+			if _, ok := index[*response.ParentID]; ok {
+				childIDs[*response.ParentID] = append(childIDs[*response.ParentID], i)
+				continue
+			}
+		}
+		roots = append(roots, i)
+	}
+
+	var build func(i int) models.TaskResponse
+	build = func(i int) models.TaskResponse {
+		node := responses[i]
+		for _, child := range childIDs[node.ID] {
+			node.Children = append(node.Children, build(child))
+		}
+		return node
+	}
+
+	result := make([]models.TaskResponse, 0, len(roots))
+	for _, i := range roots {
+		result = append(result, build(i))
+	}
+	return result
+}
+
+// CreateSubtask 在指定任务下创建子任务
+func CreateSubtask(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 获取父任务ID
+	parentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	// 绑定请求数据
+	var taskReq TaskRequest
+	if err := c.ShouldBindJSON(&taskReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务数据"})
+		return
+	}
+
+	id := uint(parentID)
+	taskReq.ParentID = &id
+	createTask(c, userID.(uint), taskReq)
+}
+
+// MoveTaskRequest 移动任务请求结构
+type MoveTaskRequest struct {
+	ParentID *uint `json:"parentId"` // 新的父任务ID，为空表示移动到顶层
+	Version  *int  `json:"version"`  // 客户端持有的版本号，也可以通过If-Match请求头提供
+}
+
+// MoveTask 将任务移动到另一个父任务下（或移动到顶层）
+func MoveTask(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 获取任务ID
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	// 查找任务
+	var task models.Task
+	if db.Where("id = ? AND user_id = ? AND workspace_id = ?", taskID, userID, currentWorkspace(c)).First(&task).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在或无权限"})
+		return
+	}
+
+	// 绑定请求数据
+	var moveReq MoveTaskRequest
+	if err := c.ShouldBindJSON(&moveReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	version, ok := requestedTaskVersion(c, moveReq.Version)
+	if !ok {
+		return
+	}
+
+	// 校验新的父任务，不能移动到自身或自己的子任务下
+	if moveReq.ParentID != nil {
+		var parent models.Task
+		if db.Where("id = ? AND user_id = ? AND workspace_id = ?", *moveReq.ParentID, task.UserID, task.WorkspaceID).First(&parent).RecordNotFound() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "父任务不存在或无权限"})
+			return
+		}
+		tree, err := loadTaskTree([]uint{task.ID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "移动任务失败"})
+			return
+		}
+		if parent.ID == task.ID || containsID(tree.descendants(task.ID), parent.ID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不能将任务移动到自身或其子任务下"})
+			return
+		}
+	}
+
+	// 更新父任务，仅当版本号与客户端持有的一致时才写入
+	tx := db.Begin()
+	query := tx.Model(&models.Task{}).Where("id = ?", task.ID)
+	if version != nil {
+		query = query.Where("version = ?", *version)
+	}
+	result := query.Updates(map[string]interface{}{
+		"parent_id": moveReq.ParentID,
+		"version":   bumpVersion,
+	})
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移动任务失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		respondTaskConflict(c, task.ID)
+		return
+	}
+	changes := []models.FieldChange{{Field: "parentId", Old: task.ParentID, New: moveReq.ParentID}}
+	if err := recordTaskEvent(tx, task.ID, userID.(uint), models.TaskEventMoved, changes); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移动任务失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移动任务失败"})
+		return
+	}
+	task.ParentID = moveReq.ParentID
+	task.Version++
+	publishTaskEvent(streamTaskUpdated, task)
+
+	respondTask(c, task)
+}
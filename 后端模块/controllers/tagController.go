@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/models"
+)
+
+// 标签颜色格式，如 #409EFF
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// 标签默认颜色
+const defaultTagColor = "#409EFF"
+
+// TagRequest 标签请求结构
+type TagRequest struct {
+	Name  string `json:"name"`  // 标签名称，创建时必填
+	Color string `json:"color"` // 标签颜色，可选
+}
+
+// GetTags 获取当前用户的全部标签
+func GetTags(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var tags []models.Tag
+	if err := db.Where("user_id = ?", userID).Order("name ASC").Find(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// CreateTag 创建标签
+func CreateTag(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 绑定请求数据
+	var tagReq TagRequest
+	if err := c.ShouldBindJSON(&tagReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的标签数据"})
+		return
+	}
+
+	tag := models.Tag{
+		UserID: userID.(uint),
+		Name:   strings.TrimSpace(tagReq.Name),
+		Color:  defaultTagColor,
+	}
+	if tag.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "标签名称不能为空"})
+		return
+	}
+	if tagReq.Color != "" {
+		if !tagColorPattern.MatchString(tagReq.Color) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的颜色，格式为 #RRGGBB"})
+			return
+		}
+		tag.Color = tagReq.Color
+	}
+
+	// 检查标签名是否已存在
+	var existing models.Tag
+	if !db.Where("user_id = ? AND name = ?", userID, tag.Name).First(&existing).RecordNotFound() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "标签已存在"})
+		return
+	}
+
+	if err := db.Create(&tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// UpdateTag 更新标签名称或颜色
+func UpdateTag(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 获取标签ID
+	tagID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的标签ID"})
+		return
+	}
+
+	// 查找标签
+	var tag models.Tag
+	if db.Where("id = ? AND user_id = ?", tagID, userID).First(&tag).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "标签不存在或无权限"})
+		return
+	}
+
+	// 绑定请求数据
+	var tagReq TagRequest
+	if err := c.ShouldBindJSON(&tagReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的标签数据"})
+		return
+	}
+
+	if name := strings.TrimSpace(tagReq.Name); name != "" && name != tag.Name {
+		var existing models.Tag
+		if !db.Where("user_id = ? AND name = ?", userID, name).First(&existing).RecordNotFound() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "标签已存在"})
+			return
+		}
+		tag.Name = name
+	}
+	if tagReq.Color != "" {
+		if !tagColorPattern.MatchString(tagReq.Color) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的颜色，格式为 #RRGGBB"})
+			return
+		}
+		tag.Color = tagReq.Color
+	}
+
+	if err := db.Save(&tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag 删除标签，并解除其与任务的关联
+func DeleteTag(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 获取标签ID
+	tagID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的标签ID"})
+		return
+	}
+
+	// 查找标签
+	var tag models.Tag
+	if db.Where("id = ? AND user_id = ?", tagID, userID).First(&tag).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "标签不存在或无权限"})
+		return
+	}
+
+	tx := db.Begin()
+	if err := tx.Exec("DELETE FROM task_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除标签失败"})
+		return
+	}
+	if err := tx.Delete(&tag).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除标签失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "标签已删除"})
+}
+
+// resolveTags 根据名称查找用户的标签，不存在的标签使用默认颜色自动创建
+func resolveTags(userID uint, names []string) ([]models.Tag, error) {
+	tags := make([]models.Tag, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		tag := models.Tag{UserID: userID, Name: name}
+		if err := db.Where(models.Tag{UserID: userID, Name: name}).
+			Attrs(models.Tag{Color: defaultTagColor}).
+			FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// findTags 根据名称查找用户已有的标签，不自动创建；有标签不存在时返回其名称
+func findTags(userID uint, names []string) ([]models.Tag, string, error) {
+	trimmed := make([]string, 0, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			trimmed = append(trimmed, name)
+		}
+	}
+	var existing []models.Tag
+	if len(trimmed) > 0 {
+		if err := db.Where("user_id = ? AND name IN (?)", userID, trimmed).Find(&existing).Error; err != nil {
+			return nil, "", err
+		}
+	}
+	byName := make(map[string]models.Tag, len(existing))
+	for _, tag := range existing {
+		byName[tag.Name] = tag
+	}
+
+	tags := make([]models.Tag, 0, len(trimmed))
+	seen := make(map[string]bool, len(trimmed))
+	for _, name := range trimmed {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		tag, ok := byName[name]
+		if !ok {
+			return nil, name, nil
+		}
+		tags = append(tags, tag)
+	}
+	return tags, "", nil
+}
+
+// splitTagNames 解析逗号分隔的标签名
+func splitTagNames(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// toTagResponses 将标签模型转换为任务响应中的标签
+func toTagResponses(tags []models.Tag) []models.TagResponse {
+	responses := make([]models.TagResponse, len(tags))
+	for i, tag := range tags {
+		responses[i] = models.TagResponse{ID: tag.ID, Name: tag.Name, Color: tag.Color}
+	}
+	return responses
+}
@@ -1,14 +1,18 @@
 package controllers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
 
 	"taskmanager/models"
+	"taskmanager/recurrence"
 )
 
 // GetTasks 获取所有任务
@@ -20,12 +24,120 @@ func GetTasks(c *gin.Context) {
 		return
 	}
 
+	// 按视图确定任务范围，默认为自己创建的任务
+	var scope *gorm.DB
+	switch view := c.DefaultQuery("view", "created"); view {
+	case "created":
+		scope = db.Where("user_id = ?", userID)
+	case "assigned", "shared":
+		scope = db.Where("id IN ?", accessibleTaskIDs(userID, view))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的视图，可选值为: created, assigned, shared"})
+		return
+	}
+
+	// 构建查询，只列出当前工作区的任务
+	query, ok := filterTaskProject(c, scope.Where("workspace_id = ?", currentWorkspace(c)))
+	if !ok {
+		return
+	}
+
+	listTasks(c, userID, query)
+}
+
+// filterTaskProject 按projectId参数筛选，none表示未归入任何项目的任务
+// 参数无效时已写入错误响应
+func filterTaskProject(c *gin.Context, query *gorm.DB) (*gorm.DB, bool) {
+	if projectID := c.Query("projectId"); projectID == "none" {
+		query = query.Where("project_id IS NULL")
+	} else if projectID != "" {
+		id, err := strconv.Atoi(projectID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的项目ID"})
+			return nil, false
+		}
+		query = query.Where("project_id = ?", id)
+	}
+	return query, true
+}
+
+// listTasks 在给定查询范围上应用通用筛选条件并返回任务列表
+// GetTasks和GetProjectTasks共用
+func listTasks(c *gin.Context, userID interface{}, query *gorm.DB) {
+	asTree := c.Query("tree") == "true"
+
+	// 解析排序和分页参数
+	page, err := parseTaskPage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, ok := filterTasks(c, query)
+	if !ok {
+		return
+	}
+
+	// 统计符合条件的任务总数
+	var total int
+	if err := query.Model(&models.Task{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务失败"})
+		return
+	}
+
+	// 按排序和分页参数获取当前页
+	var tasks []models.Task
+	if err := page.apply(query).Preload("Tags").Preload("Attachments").Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务失败"})
+		return
+	}
+
+	// 多取的一条说明还有下一页
+	result := models.TaskListResponse{Total: total, PageSize: page.pageSize}
+	if len(tasks) > page.pageSize {
+		tasks = tasks[:page.pageSize]
+		result.NextCursor = page.nextCursor(tasks[len(tasks)-1])
+	}
+	if page.cursor == nil {
+		result.Page = page.page
+	}
+
+	// 只加载当前页任务的子树和依赖关系，用于计算子任务统计、进度和阻塞状态
+	tree, err := loadTaskTree(taskIDs(tasks))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务失败"})
+		return
+	}
+
+	// 转换为响应模型
+	response := make([]models.TaskResponse, len(tasks))
+	for i, task := range tasks {
+		response[i] = toTaskResponse(task)
+		tree.fill(&response[i])
+	}
+	if err := fillPermissions(userID.(uint), tasks, response); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务失败"})
+		return
+	}
+
+	// 按层级组装为树形结构，仅在当前页内组装
+	if asTree {
+		response = nestTaskResponses(response)
+	}
+	result.Items = response
+
+	c.JSON(http.StatusOK, result)
+}
+
+// filterTasks 应用priority、completed、status、tag、tagMode筛选参数
+// 参数无效时已写入错误响应
+func filterTasks(c *gin.Context, query *gorm.DB) (*gorm.DB, bool) {
 	// 查询参数
 	priority := c.Query("priority")
 	completed := c.Query("completed")
-
-	// 构建查询
-	query := db.Where("user_id = ?", userID)
+	statuses := splitTagNames(c.Query("status"))
+	tagNames := splitTagNames(c.Query("tag"))
+	tagMode := c.DefaultQuery("tagMode", "any")
 
 	// 按优先级筛选
 	if priority != "" {
@@ -39,40 +151,60 @@ func GetTasks(c *gin.Context) {
 		query = query.Where("completed = ?", false)
 	}
 
-	// 获取任务列表，按截止日期和创建时间排序
-	var tasks []models.Task
-	if err := query.Order("CASE WHEN due_date IS NULL THEN 1 ELSE 0 END, due_date ASC, created_at DESC").Find(&tasks).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务失败"})
-		return
+	// 按工作流状态筛选，多个状态用逗号分隔
+	if len(statuses) > 0 {
+		query = query.Where("status IN (?)", statuses)
 	}
 
-	// 转换为响应模型
-	response := make([]models.TaskResponse, len(tasks))
-	for i, task := range tasks {
-		response[i] = models.TaskResponse{
-			ID:          task.ID,
-			Title:       task.Title,
-			Description: task.Description,
-			Completed:   task.Completed,
-			Priority:    task.Priority,
-			DueDate:     task.DueDate,
-			UserID:      task.UserID,
-			CreatedAt:   task.CreatedAt,
-			UpdatedAt:   task.UpdatedAt,
+	// 按标签筛选，any表示包含任一标签，all表示包含全部标签
+	// 任务只会关联其创建者的标签，按名称匹配即可同时适用于共享的任务
+	if len(tagNames) > 0 {
+		sub := db.Table("task_tags").
+			Select("task_tags.task_id").
+			Joins("JOIN tags ON tags.id = task_tags.tag_id").
+			Where("tags.name IN (?)", tagNames).
+			Group("task_tags.task_id")
+		switch tagMode {
+		case "any":
+		case "all":
+			sub = sub.Having("COUNT(DISTINCT tags.id) = ?", len(tagNames))
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的标签匹配方式，可选值为: any, all"})
+			return nil, false
 		}
+		query = query.Where("id IN ?", sub.SubQuery())
 	}
 
-	c.JSON(http.StatusOK, response)
+	return query, true
 }
 
 // TaskRequest 任务请求模型
-// 用于创建和更新任务的请求数据结构
+// 用于创建任务的请求数据结构，更新任务使用TaskPatchRequest
 type TaskRequest struct {
-	Title       string `json:"title"`       // 任务标题，创建时必填
-	Description string `json:"description"` // 任务描述，可选
-	Completed   bool   `json:"completed"`   // 是否完成，默认false
-	Priority    string `json:"priority"`    // 优先级，可选值为"low", "medium", "high"
-	DueDate     string `json:"dueDate"`     // 截止日期，字符串格式，可选
+	Title       string   `json:"title"`       // 任务标题，创建时必填
+	Description string   `json:"description"` // 任务描述，可选
+	Completed   bool     `json:"completed"`   // 是否完成，默认false
+	Status      string   `json:"status"`      // 工作流状态，可选，指定时忽略completed
+	Priority    string   `json:"priority"`    // 优先级，可选值为"low", "medium", "high"
+	DueDate     string   `json:"dueDate"`     // 截止日期，字符串格式，可选
+	ParentID    *uint    `json:"parentId"`    // 父任务ID，创建子任务时使用，可选
+	ProjectID   *uint    `json:"projectId"`   // 所属项目ID，创建时可选；子任务默认继承父任务的项目
+	Tags        []string `json:"tags"`        // 标签名称列表，不存在的标签会自动创建
+	Recurrence  *string  `json:"recurrence"`  // 重复规则，如"FREQ=WEEKLY;BYDAY=MO"，可选
+}
+
+// TaskPatchRequest 任务更新请求模型
+// 只修改请求中出现的字段，省略的字段保持不变，显式null表示清除
+type TaskPatchRequest struct {
+	Title       patchField `json:"title"`       // 任务标题，字符串，不能为空或null
+	Description patchField `json:"description"` // 任务描述，字符串，null表示清空
+	Completed   patchField `json:"completed"`   // 是否完成，布尔值，null视为false；改为当前状态可流转到的第一个对应状态
+	Status      patchField `json:"status"`      // 工作流状态，字符串，需符合流转规则，同时提供时忽略completed
+	Priority    patchField `json:"priority"`    // 优先级，null表示恢复默认的"medium"
+	DueDate     patchField `json:"dueDate"`     // 截止日期，字符串，null或空字符串表示清除
+	Tags        patchField `json:"tags"`        // 标签名称列表，替换全部标签，null或空数组表示清空
+	Recurrence  patchField `json:"recurrence"`  // 重复规则，null或空字符串表示取消重复
+	Version     *int       `json:"version"`     // 客户端持有的版本号，未提供If-Match请求头时必填
 }
 
 // CreateTask 创建新任务
@@ -91,6 +223,17 @@ func CreateTask(c *gin.Context) {
 		return
 	}
 
+	createTask(c, userID.(uint), taskReq)
+}
+
+// createTask 校验请求数据并创建任务，CreateTask和CreateSubtask共用
+func createTask(c *gin.Context, userID uint, taskReq TaskRequest) {
+	// 访客不能创建任务
+	if !requireMember(c) {
+		return
+	}
+	workspaceID := currentWorkspace(c)
+
 	// 创建任务时标题必填
 	if taskReq.Title == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "任务标题不能为空"})
@@ -121,36 +264,99 @@ func CreateTask(c *gin.Context) {
 		dueDate = &parsedTime
 	}
 
+	// 校验父任务归属
+	projectID := taskReq.ProjectID
+	if taskReq.ParentID != nil {
+		var parent models.Task
+		if db.Where("id = ? AND user_id = ? AND workspace_id = ?", *taskReq.ParentID, userID, workspaceID).First(&parent).RecordNotFound() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "父任务不存在或无权限"})
+			return
+		}
+		if projectID == nil {
+			projectID = parent.ProjectID
+		}
+	}
+
+	// 校验项目归属
+	if projectID != nil && !ownsProject(userID, workspaceID, *projectID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "项目不存在或无权限"})
+		return
+	}
+
+	// 解析重复规则
+	var recurrenceRule string
+	if taskReq.Recurrence != nil {
+		rule, err := normalizeRecurrence(*taskReq.Recurrence, dueDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的重复规则: " + err.Error()})
+			return
+		}
+		recurrenceRule = rule
+	}
+
+	// 确定工作流状态，未指定时根据completed选择第一个对应的状态
+	wf, err := loadWorkflow(db, userID, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建任务失败"})
+		return
+	}
+	state := wf.initialState(taskReq.Completed)
+	if taskReq.Status != "" {
+		var ok bool
+		if state, ok = wf.state(taskReq.Status); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务状态"})
+			return
+		}
+	}
+
+	// 解析标签
+	tags, err := resolveTags(userID, taskReq.Tags)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存标签失败"})
+		return
+	}
+
 	// 创建任务模型
 	task := models.Task{
 		Title:       taskReq.Title,
 		Description: taskReq.Description,
-		Completed:   taskReq.Completed,
+		Completed:   state.Done(),
+		Status:      state.Key,
 		Priority:    priority,
 		DueDate:     dueDate,
-		UserID:      userID.(uint),
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		ParentID:    taskReq.ParentID,
+		ProjectID:   projectID,
+		Recurrence:  recurrenceRule,
+		Occurrence:  1,
+		Tags:        tags,
 	}
 
-	// 保存任务
-	if err := db.Create(&task).Error; err != nil {
+	// 保存任务，放到看板列末尾，并记录初始字段
+	tx := db.Begin()
+	if task.Rank, err = placeInColumn(tx, userID, task.Status, 0, nil, nil); err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建任务失败"})
 		return
 	}
-
-	// 转换为响应模型
-	response := models.TaskResponse{
-		ID:          task.ID,
-		Title:       task.Title,
-		Description: task.Description,
-		Completed:   task.Completed,
-		Priority:    task.Priority,
-		DueDate:     task.DueDate,
-		UserID:      task.UserID,
-		CreatedAt:   task.CreatedAt,
-		UpdatedAt:   task.UpdatedAt,
+	if err := tx.Create(&task).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建任务失败"})
+		return
 	}
+	if err := recordTaskEvent(tx, task.ID, userID, models.TaskEventCreated, diffTask(models.Task{Tags: []models.Tag{}}, task)); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建任务失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建任务失败"})
+		return
+	}
+	publishTaskEvent(streamTaskCreated, task)
 
-	c.JSON(http.StatusOK, response)
+	respondTask(c, task)
 }
 
 // UpdateTask 更新任务状态
@@ -162,78 +368,285 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
-	// 获取任务ID
-	taskID, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+	// 查找任务，创建者、负责人和拥有编辑权限的用户可以修改
+	task, ok := findTask(c, accessEdit)
+	if !ok {
 		return
 	}
 
-	// 查找任务
-	var task models.Task
-	if db.Where("id = ? AND user_id = ?", taskID, userID).First(&task).RecordNotFound() {
-		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在或无权限"})
+	// 绑定更新数据，只有请求中出现的字段才会被修改
+	var patch TaskPatchRequest
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的更新数据"})
 		return
 	}
-
-	// 绑定更新数据
-	var updateData TaskRequest
-	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的更新数据"})
+	version, ok := requestedTaskVersion(c, patch.Version)
+	if !ok {
 		return
 	}
 
-	// 更新基本字段
-	if updateData.Title != "" {
-		task.Title = updateData.Title
+	// 保留修改前的任务，用于记录变更历史
+	before := task
+
+	// 更新标题，标题不能清空
+	if patch.Title.Set {
+		title, err := patch.Title.stringValue()
+		if err != nil || title == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "任务标题不能为空"})
+			return
+		}
+		task.Title = title
 	}
 
-	task.Description = updateData.Description
-	task.Completed = updateData.Completed
+	// 更新描述
+	if patch.Description.Set {
+		description, err := patch.Description.stringValue()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务描述"})
+			return
+		}
+		task.Description = description
+	}
 
-	// 解析截止日期
-	if updateData.DueDate != "" {
-		parsedTime, err := parseTime(updateData.DueDate)
+	// 更新工作流状态，completed由状态分类决定
+	if patch.Status.Set || patch.Completed.Set {
+		wf, err := loadWorkflow(db, task.UserID, task.ProjectID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务失败"})
+			return
+		}
+		current, _ := wf.state(task.Status)
+		if patch.Status.Set {
+			key, err := patch.Status.stringValue()
+			state, ok := wf.state(key)
+			if err != nil || !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务状态"})
+				return
+			}
+			if !current.CanTransition(state.Key) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "不允许从「" + current.Name + "」流转到「" + state.Name + "」"})
+				return
+			}
+			task.Status = state.Key
+			task.Completed = state.Done()
+		} else {
+			completed, err := patch.Completed.boolValue()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "无效的完成状态"})
+				return
+			}
+			// 兼容只修改completed的客户端，改为当前状态可以流转到的第一个对应状态
+			if completed != task.Completed {
+				state, ok := wf.reachableState(current, completed)
+				if !ok {
+					target := "未完成"
+					if completed {
+						target = "已完成"
+					}
+					c.JSON(http.StatusBadRequest, gin.H{"error": "不允许从「" + current.Name + "」流转到" + target + "状态"})
+					return
+				}
+				task.Status = state.Key
+				task.Completed = state.Done()
+			}
+		}
+	}
+	completing := task.Completed && !before.Completed
+
+	// 完成被阻塞的任务需要确认
+	if completing && !checkOpenBlockers(c, task) {
+		return
+	}
+
+	// 解析截止日期，null或空字符串表示清除
+	if patch.DueDate.Set {
+		dueDate, err := patch.DueDate.stringValue()
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的日期格式"})
 			return
 		}
-		task.DueDate = &parsedTime
-	} else {
-		task.DueDate = nil
+		if dueDate == "" {
+			task.DueDate = nil
+		} else {
+			parsedTime, err := parseTime(dueDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "无效的日期格式"})
+				return
+			}
+			task.DueDate = &parsedTime
+		}
 	}
 
-	// 更新优先级
-	if updateData.Priority != "" {
-		switch models.Priority(updateData.Priority) {
+	// 更新优先级，null表示恢复默认优先级
+	if patch.Priority.Set {
+		priority, err := patch.Priority.stringValue()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的优先级，可选值为: low, medium, high"})
+			return
+		}
+		if priority == "" {
+			priority = string(models.Medium)
+		}
+		switch models.Priority(priority) {
 		case models.Low, models.Medium, models.High:
-			task.Priority = models.Priority(updateData.Priority)
+			task.Priority = models.Priority(priority)
 		default:
 			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的优先级，可选值为: low, medium, high"})
 			return
 		}
 	}
 
-	// 保存更新
-	if err := db.Save(&task).Error; err != nil {
+	// 更新重复规则，null或空字符串表示取消重复
+	if patch.Recurrence.Set {
+		value, err := patch.Recurrence.stringValue()
+		if err == nil {
+			value, err = normalizeRecurrence(value, task.DueDate)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的重复规则: " + err.Error()})
+			return
+		}
+		task.Recurrence = value
+	}
+
+	// 解析标签，null或空数组表示清空
+	var tags []models.Tag
+	if patch.Tags.Set {
+		names, err := patch.Tags.stringsValue()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的标签列表"})
+			return
+		}
+		// 标签属于任务创建者，其他可编辑的用户只能使用创建者已有的标签，不能在其标签列表中新建
+		if task.UserID == userID.(uint) {
+			tags, err = resolveTags(task.UserID, names)
+		} else {
+			var missing string
+			tags, missing, err = findTags(task.UserID, names)
+			if err == nil && missing != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "标签「" + missing + "」不存在，只有任务创建者可以添加新标签"})
+				return
+			}
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存标签失败"})
+			return
+		}
+		if err := db.Model(&before).Association("Tags").Find(&before.Tags).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务失败"})
+			return
+		}
+	}
+
+	// 保存更新，仅当版本号与客户端持有的一致时才写入
+	// 状态变化时放到新列的末尾
+	tx := db.Begin()
+	if task.Status != before.Status {
+		rank, err := placeInColumn(tx, task.UserID, task.Status, task.ID, nil, nil)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务失败"})
+			return
+		}
+		task.Rank = rank
+	}
+	query := tx.Model(&models.Task{}).Where("id = ?", task.ID)
+	if version != nil {
+		query = query.Where("version = ?", *version)
+	}
+	result := query.Updates(map[string]interface{}{
+		"title":       task.Title,
+		"description": task.Description,
+		"completed":   task.Completed,
+		"status":      task.Status,
+		"board_rank":  task.Rank,
+		"priority":    task.Priority,
+		"due_date":    task.DueDate,
+		"recurrence":  task.Recurrence,
+		"version":     bumpVersion,
+	})
+	if result.Error != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务失败"})
 		return
 	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		respondTaskConflict(c, task.ID)
+		return
+	}
 
-	// 转换为响应模型
-	response := models.TaskResponse{
-		ID:          task.ID,
-		Title:       task.Title,
-		Description: task.Description,
-		Completed:   task.Completed,
-		Priority:    task.Priority,
-		DueDate:     task.DueDate,
-		UserID:      task.UserID,
-		CreatedAt:   task.CreatedAt,
-		UpdatedAt:   task.UpdatedAt,
+	// 替换标签，未提供tags字段时保持不变
+	if patch.Tags.Set {
+		if err := tx.Model(&task).Association("Tags").Replace(tags).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存标签失败"})
+			return
+		}
+		task.Tags = tags
+	}
+
+	// 截止日期变化后重新计算提醒时间
+	if !sameTime(before.DueDate, task.DueDate) {
+		if err := rescheduleReminders(tx, task); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务失败"})
+			return
+		}
 	}
 
-	c.JSON(http.StatusOK, response)
+	// 记录变更历史，没有字段变化时不记录
+	if changes := diffTask(before, task); len(changes) > 0 {
+		action := models.TaskEventUpdated
+		if completing {
+			action = models.TaskEventCompleted
+		} else if before.Completed && !task.Completed {
+			action = models.TaskEventReopened
+		}
+		if err := recordTaskEvent(tx, task.ID, userID.(uint), action, changes); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务失败"})
+			return
+		}
+	}
+
+	// 完成重复任务时在同一事务中生成下一次任务
+	var next *models.Task
+	if completing {
+		var err error
+		if next, err = spawnNextOccurrence(tx, &task, userID.(uint)); err != nil {
+			tx.Rollback()
+			if err == errOccurrenceSpawned {
+				respondTaskConflict(c, task.ID)
+				return
+			}
+			log.Printf("生成下一次重复任务失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成下一次重复任务失败"})
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务失败"})
+		return
+	}
+	if err := db.First(&task, task.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务失败"})
+		return
+	}
+	publishTaskEvent(streamTaskUpdated, task)
+	if next != nil {
+		publishTaskEvent(streamTaskCreated, *next)
+	}
+
+	// 完成父任务时可选择同时完成全部子任务
+	if completing && c.Query("cascade") == "true" {
+		if err := completeDescendants(task, userID.(uint)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新子任务失败"})
+			return
+		}
+	}
+
+	respondTask(c, task)
 }
 
 // parseTime 尝试解析多种格式的日期字符串
@@ -297,25 +710,320 @@ func DeleteTask(c *gin.Context) {
 		return
 	}
 
-	// 获取任务ID
-	taskID, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+	// 查找任务，只有创建者可以删除，共享的用户返回403
+	task, ok := findTask(c, accessOwner)
+	if !ok {
 		return
 	}
 
-	// 查找任务
-	var task models.Task
-	if db.Where("id = ? AND user_id = ?", taskID, userID).First(&task).RecordNotFound() {
-		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在或无权限"})
+	// 版本号可以放在请求体中，也可以通过If-Match请求头或查询参数提供
+	var deleteReq struct {
+		Version *int `json:"version"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&deleteReq); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+			return
+		}
+	}
+	version, ok := requestedTaskVersion(c, deleteReq.Version)
+	if !ok {
 		return
 	}
 
-	// 删除任务
-	if err := db.Delete(&task).Error; err != nil {
+	// 删除任务及其全部子任务
+	tree, err := loadTaskTree([]uint{task.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除任务失败"})
+		return
+	}
+
+	// 先按版本号删除任务本身，版本不一致时不做任何修改
+	// 任务移入回收站，子任务使用相同的删除批次，以便在回收站中整体恢复
+	deletion := map[string]interface{}{
+		"deleted_at":   time.Now(),
+		"delete_batch": uuid.New().String(),
+		"version":      bumpVersion,
+	}
+	tx := db.Begin()
+	query := tx.Model(&models.Task{}).Where("id = ?", task.ID)
+	if version != nil {
+		query = query.Where("version = ?", *version)
+	}
+	result := query.Updates(deletion)
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除任务失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		respondTaskConflict(c, task.ID)
+		return
+	}
+	descendants := tree.descendants(task.ID)
+	if len(descendants) > 0 {
+		if err := tx.Model(&models.Task{}).Where("id IN (?)", descendants).Updates(deletion).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "删除任务失败"})
+			return
+		}
+	}
+	if err := recordTaskEvents(tx, append([]uint{task.ID}, descendants...), userID.(uint), models.TaskEventDeleted, nil); err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除任务失败"})
 		return
 	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除任务失败"})
+		return
+	}
+	var deleted []models.Task
+	if err := db.Unscoped().Where("id IN (?)", append([]uint{task.ID}, descendants...)).Find(&deleted).Error; err != nil {
+		log.Printf("加载已删除的任务失败: %v", err)
+	}
+	publishTaskEvents(streamTaskDeleted, deleted)
 
 	c.JSON(http.StatusOK, gin.H{"message": "任务已删除"})
 }
+
+// findTask 根据URL参数查找当前用户有权访问的任务，失败时已写入错误响应
+// 无权查看时返回404，可以查看但权限低于need时返回403
+func findTask(c *gin.Context, need taskAccess) (models.Task, bool) {
+	var task models.Task
+
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return task, false
+	}
+
+	// 获取任务ID
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return task, false
+	}
+
+	if db.Where("id = ? AND workspace_id = ?", taskID, currentWorkspace(c)).First(&task).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在或无权限"})
+		return task, false
+	}
+	levels, err := taskAccessLevels(userID.(uint), []models.Task{task})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务权限失败"})
+		return task, false
+	}
+	switch access := levels[task.ID]; {
+	case access == accessNone:
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在或无权限"})
+		return task, false
+	case access < need && need == accessOwner:
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有任务创建者可以执行该操作"})
+		return task, false
+	case access < need:
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有修改该任务的权限"})
+		return task, false
+	}
+	return task, true
+}
+
+// toTaskResponse 将任务模型转换为响应模型
+func toTaskResponse(task models.Task) models.TaskResponse {
+	return models.TaskResponse{
+		ID:               task.ID,
+		Title:            task.Title,
+		Description:      task.Description,
+		Completed:        task.Completed,
+		Status:           task.Status,
+		Rank:             task.Rank,
+		Priority:         task.Priority,
+		DueDate:          task.DueDate,
+		UserID:           task.UserID,
+		WorkspaceID:      task.WorkspaceID,
+		ParentID:         task.ParentID,
+		ProjectID:        task.ProjectID,
+		Recurrence:       task.Recurrence,
+		Occurrence:       task.Occurrence,
+		NextOccurrenceID: task.NextOccurrenceID,
+		Version:          task.Version,
+		Tags:             toTagResponses(task.Tags),
+		Attachments:      toFileResponses(task.Attachments),
+		CreatedAt:        task.CreatedAt,
+		UpdatedAt:        task.UpdatedAt,
+		Assignees:        []models.UserSummary{},
+		BlockedBy:        []uint{},
+		Blocks:           []uint{},
+	}
+}
+
+// respondTask 返回单个任务，附带子任务统计、进度和当前用户的权限，并在ETag中返回版本号
+func respondTask(c *gin.Context, task models.Task) {
+	response := []models.TaskResponse{buildTaskResponse(task)}
+	if userID, exists := c.Get("userId"); exists {
+		if err := fillPermissions(userID.(uint), []models.Task{task}, response); err != nil {
+			log.Printf("加载任务权限失败: %v", err)
+		}
+	}
+	c.Header("ETag", taskETag(task))
+	c.JSON(http.StatusOK, response[0])
+}
+
+// buildTaskResponse 加载任务标签、附件和层级统计，生成单个任务的响应
+func buildTaskResponse(task models.Task) models.TaskResponse {
+	if err := db.Model(&task).Association("Tags").Find(&task.Tags).Error; err != nil {
+		log.Printf("加载任务标签失败: %v", err)
+	}
+	if err := db.Model(&task).Order("id ASC").Association("Attachments").Find(&task.Attachments).Error; err != nil {
+		log.Printf("加载任务附件失败: %v", err)
+	}
+
+	response := toTaskResponse(task)
+	if tree, err := loadTaskTree([]uint{task.ID}); err == nil {
+		tree.fill(&response)
+	} else {
+		log.Printf("加载任务层级失败: %v", err)
+	}
+	return response
+}
+
+// completeDescendants 将任务的全部未完成子任务改为所在工作流的第一个已完成状态
+func completeDescendants(task models.Task, actorID uint) error {
+	tree, err := loadTaskTree([]uint{task.ID})
+	if err != nil {
+		return err
+	}
+	var ids []uint
+	for _, id := range tree.descendants(task.ID) {
+		if !tree.completed[id] {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var tasks []models.Task
+	if err := db.Select("id, title, user_id, workspace_id, parent_id, project_id, status, completed, board_rank, version").
+		Where("id IN (?)", ids).Order("id ASC").Find(&tasks).Error; err != nil {
+		return err
+	}
+
+	tx := db.Begin()
+	for i := range tasks {
+		task := &tasks[i]
+		wf, err := loadWorkflow(tx, task.UserID, task.ProjectID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		state := wf.initialState(true)
+		rank, err := placeInColumn(tx, task.UserID, state.Key, task.ID, nil, nil)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := applyTaskState(tx, task, actorID, state, rank); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	publishTaskEvents(streamTaskUpdated, tasks)
+	return nil
+}
+
+// normalizeRecurrence 校验并规范化重复规则，空字符串表示不重复
+// 按截止日期每月重复且未指定日期时，固定为截止日期当天，避免月末日期逐月漂移；
+// 截止日期在29-31日时，没有这一天的月份取当月最后一天（见recurrence.Rule）
+func normalizeRecurrence(value string, dueDate *time.Time) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	rule, err := recurrence.Parse(value)
+	if err != nil {
+		return "", err
+	}
+	if rule.Freq == recurrence.Monthly && rule.ByMonthDay == 0 && !rule.FromCompletion && dueDate != nil {
+		rule.ByMonthDay = dueDate.Day()
+	}
+	return rule.String(), nil
+}
+
+// errOccurrenceSpawned 下一次任务已由并发的另一次完成生成
+var errOccurrenceSpawned = errors.New("下一次重复任务已生成")
+
+// spawnNextOccurrence 在事务中根据重复规则为已完成的任务生成下一次任务，不需要生成时返回nil
+// 每个任务只会生成一次：只在next_occurrence_id为空时写入，已被并发的请求生成时返回errOccurrenceSpawned，
+// 调用方应回滚整个事务；生成的任务在事务提交后由调用方推送
+func spawnNextOccurrence(tx *gorm.DB, task *models.Task, actorID uint) (*models.Task, error) {
+	if task.Recurrence == "" || task.NextOccurrenceID != nil {
+		return nil, nil
+	}
+
+	rule, err := recurrence.Parse(task.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	nextDue, ok := rule.Next(task.DueDate, time.Now(), task.Occurrence)
+	if !ok {
+		return nil, nil
+	}
+
+	var tags []models.Tag
+	if err := tx.Model(task).Association("Tags").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	wf, err := loadWorkflow(tx, task.UserID, task.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	next := models.Task{
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      wf.initialState(false).Key,
+		Priority:    task.Priority,
+		DueDate:     &nextDue,
+		UserID:      task.UserID,
+		WorkspaceID: task.WorkspaceID,
+		ParentID:    task.ParentID,
+		ProjectID:   task.ProjectID,
+		Recurrence:  task.Recurrence,
+		Occurrence:  task.Occurrence + 1,
+		Tags:        tags,
+	}
+
+	if next.Rank, err = placeInColumn(tx, next.UserID, next.Status, 0, nil, nil); err != nil {
+		return nil, err
+	}
+	if err := tx.Create(&next).Error; err != nil {
+		return nil, err
+	}
+	if err := recordTaskEvent(tx, next.ID, actorID, models.TaskEventCreated, diffTask(models.Task{Tags: []models.Tag{}}, next)); err != nil {
+		return nil, err
+	}
+	if err := copyTaskAccess(tx, task.ID, next.ID); err != nil {
+		return nil, err
+	}
+	if err := copyTaskReminders(tx, task.ID, next); err != nil {
+		return nil, err
+	}
+	result := tx.Model(&models.Task{}).Where("id = ? AND next_occurrence_id IS NULL", task.ID).Updates(map[string]interface{}{
+		"next_occurrence_id": next.ID,
+		"version":            bumpVersion,
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errOccurrenceSpawned
+	}
+	task.Version++
+	task.NextOccurrenceID = &next.ID
+	return &next, nil
+}
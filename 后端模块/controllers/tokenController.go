@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/config"
+	"taskmanager/models"
+	"taskmanager/token"
+)
+
+// TokenPair 登录或刷新后返回给客户端的令牌对
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"` // 访问令牌剩余有效秒数
+}
+
+// RefreshRequest 刷新令牌/登出请求结构
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// issueTokenPair 为用户签发短期访问令牌和新的刷新令牌
+// 刷新令牌只以摘要形式通过tx落库
+func issueTokenPair(tx *gorm.DB, userID uint) (TokenPair, string, error) {
+	jwtConfig := config.GetConfig().JWT
+
+	// 访问令牌，带jti用于吊销
+	accessToken, _, err := token.IssueAccessToken(userID)
+	if err != nil {
+		return TokenPair{}, "", err
+	}
+
+	// 刷新令牌为随机串
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return TokenPair{}, "", err
+	}
+	record := models.RefreshToken{
+		UserID:    userID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(jwtConfig.RefreshTTL),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return TokenPair{}, "", err
+	}
+
+	return TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(jwtConfig.AccessTTL.Seconds()),
+	}, record.TokenHash, nil
+}
+
+// generateRefreshToken 生成32字节随机刷新令牌
+func generateRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken 计算令牌的SHA-256摘要
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshToken 使用刷新令牌换取新的令牌对
+// 旧的刷新令牌会被吊销；如果已吊销的令牌被再次使用，视为泄露并吊销该用户的全部刷新令牌
+func RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少刷新令牌"})
+		return
+	}
+
+	var record models.RefreshToken
+	if db.Where("token_hash = ?", hashToken(req.RefreshToken)).First(&record).RecordNotFound() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的刷新令牌"})
+		return
+	}
+
+	now := time.Now()
+	if record.RevokedAt != nil {
+		if record.ReplacedBy != "" {
+			log.Printf("检测到已轮换的刷新令牌被重复使用，吊销用户 %d 的全部刷新令牌", record.UserID)
+			revokeUserRefreshTokens(record.UserID)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "刷新令牌已失效"})
+		return
+	}
+	if !record.IsActive(now) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "刷新令牌已过期"})
+		return
+	}
+
+	// 吊销旧令牌、签发新令牌和记录替换关系在同一事务中完成，失败时旧令牌仍然有效
+	// 条件更新保证同一刷新令牌只能被轮换一次
+	tx := db.Begin()
+	result := tx.Model(&models.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", record.ID).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刷新令牌失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "刷新令牌已失效"})
+		return
+	}
+
+	pair, newHash, err := issueTokenPair(tx, record.UserID)
+	if err != nil {
+		tx.Rollback()
+		log.Printf("刷新令牌签发失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法生成token"})
+		return
+	}
+	if err := tx.Model(&models.RefreshToken{}).Where("id = ?", record.ID).Update("replaced_by", newHash).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刷新令牌失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刷新令牌失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pair)
+}
+
+// Logout 登出
+// 吊销当前访问令牌，并吊销请求体中携带的刷新令牌（可选）
+func Logout(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 吊销当前访问令牌
+	if jti := c.GetString("tokenId"); jti != "" {
+		expiresAt, _ := c.Get("tokenExpiresAt")
+		exp, ok := expiresAt.(time.Time)
+		if !ok {
+			exp = time.Now().Add(token.AccessTTL())
+		}
+		revoked := models.RevokedToken{
+			JTI:       jti,
+			UserID:    userID.(uint),
+			ExpiresAt: exp,
+		}
+		if err := db.Where(models.RevokedToken{JTI: jti}).FirstOrCreate(&revoked).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "登出失败"})
+			return
+		}
+	}
+
+	// 吊销刷新令牌，请求体可以为空
+	var req RefreshRequest
+	_ = c.ShouldBindJSON(&req)
+	if req.RefreshToken != "" {
+		db.Model(&models.RefreshToken{}).
+			Where("token_hash = ? AND user_id = ? AND revoked_at IS NULL", hashToken(req.RefreshToken), userID).
+			Update("revoked_at", time.Now())
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已登出"})
+}
+
+// revokeUserRefreshTokens 吊销用户所有未失效的刷新令牌
+func revokeUserRefreshTokens(userID uint) {
+	if err := db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		log.Printf("吊销用户 %d 的刷新令牌失败: %v", userID, err)
+	}
+}
@@ -0,0 +1,478 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/config"
+	"taskmanager/models"
+)
+
+// 回收站中任务的保留天数，0表示不自动清理，由InitTrash设置
+var trashRetentionDays int
+
+// trashTree 回收站中任务的层级信息
+// 一起删除的父子任务删除批次相同，作为一个整体恢复或清除
+type trashTree struct {
+	parent    map[uint]uint
+	children  map[uint][]uint
+	deletedAt map[uint]time.Time
+	batchOf   map[uint]string
+}
+
+// InitTrash 设置回收站保留天数，并启动后台定时清理过期任务
+func InitTrash(cfg config.TrashConfig) {
+	trashRetentionDays = cfg.RetentionDays
+	if trashRetentionDays == 0 {
+		log.Println("回收站自动清理已关闭")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.PurgeInterval)
+		defer ticker.Stop()
+		for {
+			purgeExpiredTrash()
+			<-ticker.C
+		}
+	}()
+}
+
+// GetTrash 获取回收站中的任务，按删除时间倒序
+// 随父任务一起删除的子任务不单独列出，只计入 deletedSubtaskCount
+func GetTrash(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 解析分页参数
+	pageSize, err := parsePageSize(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := parsePageNumber(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tree, err := loadTrashTree(userID, currentWorkspace(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取回收站失败"})
+		return
+	}
+
+	// 找出每次删除操作的根任务并排序
+	var roots []uint
+	for id := range tree.deletedAt {
+		if tree.isRoot(id) {
+			roots = append(roots, id)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		a, b := tree.deletedAt[roots[i]], tree.deletedAt[roots[j]]
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return roots[i] > roots[j]
+	})
+
+	result := models.TrashListResponse{
+		Items:    []models.TrashTaskResponse{},
+		Total:    len(roots),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	start := (page - 1) * pageSize
+	if start >= len(roots) {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	end := start + pageSize
+	if end > len(roots) {
+		end = len(roots)
+	}
+	roots = roots[start:end]
+
+	// 加载当前页的任务详情
+	var tasks []models.Task
+	if err := db.Unscoped().Where("id IN (?)", roots).Preload("Tags").Preload("Attachments").Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取回收站失败"})
+		return
+	}
+	byID := make(map[uint]models.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+
+	for _, id := range roots {
+		task, ok := byID[id]
+		if !ok || task.DeletedAt == nil {
+			continue
+		}
+		item := models.TrashTaskResponse{
+			TaskResponse:        toTaskResponse(task),
+			DeletedAt:           *task.DeletedAt,
+			DeletedSubtaskCount: len(tree.batch(id)) - 1,
+		}
+		if trashRetentionDays > 0 {
+			purgeAt := task.DeletedAt.AddDate(0, 0, trashRetentionDays)
+			item.PurgeAt = &purgeAt
+		}
+		result.Items = append(result.Items, item)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RestoreTask 从回收站恢复任务及随它一起删除的子任务
+// 原父任务已不存在时恢复为顶层任务，原项目已删除时移出项目
+func RestoreTask(c *gin.Context) {
+	task, tree, ok := findTrashedTask(c)
+	if !ok {
+		return
+	}
+
+	updates := map[string]interface{}{}
+	var changes []models.FieldChange
+	if task.ParentID != nil {
+		var count int
+		db.Model(&models.Task{}).Where("id = ? AND user_id = ?", *task.ParentID, task.UserID).Count(&count)
+		if count == 0 {
+			updates["parent_id"] = nil
+			changes = append(changes, models.FieldChange{Field: "parentId", Old: task.ParentID, New: nil})
+		}
+	}
+	if task.ProjectID != nil && !ownsProject(task.UserID, task.WorkspaceID, *task.ProjectID) {
+		updates["project_id"] = nil
+		changes = append(changes, models.FieldChange{Field: "projectId", Old: task.ProjectID, New: nil})
+	}
+	batch := tree.batch(task.ID)
+
+	tx := db.Begin()
+	if err := tx.Unscoped().Model(&models.Task{}).Where("id IN (?)", batch).Updates(map[string]interface{}{
+		"deleted_at":   nil,
+		"delete_batch": "",
+		"version":      bumpVersion,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复任务失败"})
+		return
+	}
+	if len(updates) > 0 {
+		if err := tx.Model(&models.Task{}).Where("id = ?", task.ID).Updates(updates).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复任务失败"})
+			return
+		}
+	}
+	// 只有根任务可能被移出原父任务或项目
+	if err := recordTaskEvent(tx, task.ID, task.UserID, models.TaskEventRestored, changes); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复任务失败"})
+		return
+	}
+	if err := recordTaskEvents(tx, batch[1:], task.UserID, models.TaskEventRestored, nil); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复任务失败"})
+		return
+	}
+	// 移出项目后改用默认工作流
+	if err := syncTaskStatuses(tx, task.UserID, batch); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复任务失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复任务失败"})
+		return
+	}
+
+	if err := db.First(&task, task.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复任务失败"})
+		return
+	}
+	// 恢复的任务重新出现在列表中，按新建推送
+	var restored []models.Task
+	if err := db.Where("id IN (?)", batch).Find(&restored).Error; err != nil {
+		log.Printf("加载恢复的任务失败: %v", err)
+	}
+	publishTaskEvents(streamTaskCreated, restored)
+	respondTask(c, task)
+}
+
+// PurgeTask 永久删除回收站中的任务及随它一起删除的子任务
+func PurgeTask(c *gin.Context) {
+	task, tree, ok := findTrashedTask(c)
+	if !ok {
+		return
+	}
+
+	if err := purgeTasks(tree.batch(task.ID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "永久删除任务失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "任务已永久删除"})
+}
+
+// EmptyTrash 清空回收站，永久删除当前用户回收站中的全部任务
+func EmptyTrash(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var ids []uint
+	if err := db.Unscoped().Model(&models.Task{}).
+		Where("user_id = ? AND workspace_id = ? AND deleted_at IS NOT NULL", userID, currentWorkspace(c)).
+		Pluck("id", &ids).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "清空回收站失败"})
+		return
+	}
+	if err := purgeTasks(ids); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "清空回收站失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "回收站已清空", "count": len(ids)})
+}
+
+// findTrashedTask 根据URL参数查找当前用户回收站中的任务，失败时已写入错误响应
+func findTrashedTask(c *gin.Context) (models.Task, *trashTree, bool) {
+	var task models.Task
+
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return task, nil, false
+	}
+
+	// 获取任务ID
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return task, nil, false
+	}
+
+	if db.Unscoped().Where("id = ? AND user_id = ? AND workspace_id = ? AND deleted_at IS NOT NULL", taskID, userID, currentWorkspace(c)).
+		First(&task).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "回收站中不存在该任务"})
+		return task, nil, false
+	}
+
+	tree, err := loadTrashTree(userID, task.WorkspaceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "加载回收站失败"})
+		return task, nil, false
+	}
+	return task, tree, true
+}
+
+// loadTrashTree 加载用户在工作区回收站中全部任务的层级关系
+func loadTrashTree(userID interface{}, workspaceID uint) (*trashTree, error) {
+	var tasks []models.Task
+	if err := db.Unscoped().Select("id, parent_id, deleted_at, delete_batch").
+		Where("user_id = ? AND workspace_id = ? AND deleted_at IS NOT NULL", userID, workspaceID).
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	tree := &trashTree{
+		parent:    make(map[uint]uint, len(tasks)),
+		children:  make(map[uint][]uint),
+		deletedAt: make(map[uint]time.Time, len(tasks)),
+		batchOf:   make(map[uint]string, len(tasks)),
+	}
+	for _, task := range tasks {
+		var parentID uint
+		if task.ParentID != nil {
+			parentID = *task.ParentID
+		}
+		tree.parent[task.ID] = parentID
+		tree.deletedAt[task.ID] = *task.DeletedAt
+		// 增加删除批次之前删除的任务没有批次，仍按删除时间判断
+		tree.batchOf[task.ID] = task.DeleteBatch
+		if task.DeleteBatch == "" {
+			tree.batchOf[task.ID] = task.DeletedAt.UTC().Format(time.RFC3339Nano)
+		}
+		if parentID != 0 {
+			tree.children[parentID] = append(tree.children[parentID], task.ID)
+		}
+	}
+	return tree, nil
+}
+
+// isRoot 判断任务是否为某次删除操作的根任务：父任务未被删除，或不是与父任务一起删除的
+func (t *trashTree) isRoot(id uint) bool {
+	parentBatch, ok := t.batchOf[t.parent[id]]
+	return !ok || parentBatch != t.batchOf[id]
+}
+
+// batch 返回与任务在同一次操作中删除的任务ID，包括任务本身
+func (t *trashTree) batch(id uint) []uint {
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range t.children[ids[i]] {
+			if t.batchOf[child] == t.batchOf[id] {
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// purgeTasks 永久删除任务及其标签关联、依赖关系、负责人、共享记录、变更历史、提醒、通知、评论和附件
+// 直接上传到这些任务的文件不再被其他任务引用时一并删除，MinIO中的对象在事务提交后删除
+func purgeTasks(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	tx := db.Begin()
+	if err := tx.Exec("DELETE FROM task_tags WHERE task_id IN (?)", ids).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	objectKeys, err := purgeAttachments(tx, ids)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Where("task_id IN (?)", ids).Delete(&models.TaskEvent{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Where("task_id IN (?) OR blocker_id IN (?)", ids, ids).Delete(&models.TaskDependency{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Where("task_id IN (?)", ids).Delete(&models.TaskAssignee{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Where("task_id IN (?)", ids).Delete(&models.TaskShare{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	var reminderIDs []uint
+	if err := tx.Model(&models.TaskReminder{}).Where("task_id IN (?)", ids).Pluck("id", &reminderIDs).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := deleteReminders(tx, reminderIDs); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Where("task_id IN (?)", ids).Delete(&models.Notification{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	// 评论及其提及记录
+	commentIDs := tx.Unscoped().Model(&models.Comment{}).Select("id").Where("task_id IN (?)", ids).SubQuery()
+	if err := tx.Where("comment_id IN ?", commentIDs).Delete(&models.CommentMention{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Unscoped().Where("task_id IN (?)", ids).Delete(&models.Comment{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	// 清除其他任务指向这些任务的引用
+	if err := tx.Unscoped().Model(&models.Task{}).Where("next_occurrence_id IN (?)", ids).
+		UpdateColumn("next_occurrence_id", nil).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Unscoped().Where("id IN (?)", ids).Delete(&models.Task{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	// 对象删除失败只记录日志，不影响任务的删除
+	for _, key := range objectKeys {
+		removeObject(key)
+	}
+	return nil
+}
+
+// purgeAttachments 移除任务的附件关联，删除直接上传到这些任务且不再被引用的文件
+// 返回需要从MinIO中删除的对象键
+func purgeAttachments(tx *gorm.DB, ids []uint) ([]string, error) {
+	if err := tx.Exec("DELETE FROM task_attachments WHERE task_id IN (?)", ids).Error; err != nil {
+		return nil, err
+	}
+
+	var files []models.File
+	if err := tx.Unscoped().Where("task_id IN (?)", ids).Find(&files).Error; err != nil {
+		return nil, err
+	}
+
+	var objectKeys []string
+	for _, file := range files {
+		var count int
+		if err := tx.Table("task_attachments").Where("file_id = ?", file.ID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			// 仍被其他任务引用，保留为普通文件
+			if err := tx.Unscoped().Model(&file).UpdateColumn("task_id", nil).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := tx.Unscoped().Delete(&file).Error; err != nil {
+			return nil, err
+		}
+		if file.DeletedAt == nil {
+			objectKeys = append(objectKeys, file.ObjectKey)
+		}
+	}
+	return objectKeys, nil
+}
+
+// purgeExpiredTrash 永久删除在回收站中超过保留天数的任务
+func purgeExpiredTrash() {
+	cutoff := time.Now().AddDate(0, 0, -trashRetentionDays)
+
+	var ids []uint
+	if err := db.Unscoped().Model(&models.Task{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Pluck("id", &ids).Error; err != nil {
+		log.Printf("查询过期的回收站任务失败: %v", err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	// 分批删除，避免单条语句过大
+	const batchSize = 500
+	for start := 0; start < len(ids); start += batchSize {
+		end := start + batchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := purgeTasks(ids[start:end]); err != nil {
+			log.Printf("清理回收站失败: %v", err)
+			return
+		}
+	}
+	log.Printf("已清理回收站中超过%d天的任务%d个", trashRetentionDays, len(ids))
+}
@@ -9,9 +9,7 @@ import (
 	"net/http"
 	"path/filepath"
 	"strings"
-	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7"
@@ -21,15 +19,6 @@ import (
 	"taskmanager/models"
 )
 
-// 密钥，实际应用中应该从配置文件中读取
-var jwtKey = []byte("your_secret_key")
-
-// 定义JWT的Claims结构
-type Claims struct {
-	UserID uint `json:"userId"`
-	jwt.StandardClaims
-}
-
 // RegisterRequest 用户注册请求结构
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required"`
@@ -88,9 +77,22 @@ func Register(c *gin.Context) {
 	user.Password = string(hashedPassword)
 	log.Printf("密码加密成功, 加密后长度: %d", len(user.Password))
 
-	// 创建用户
+	// 创建用户及其个人工作区
 	log.Printf("开始创建用户: %s", user.Username)
-	if err := db.Create(&user).Error; err != nil {
+	tx := db.Begin()
+	if err := tx.Create(&user).Error; err != nil {
+		tx.Rollback()
+		log.Printf("用户创建失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "用户创建失败"})
+		return
+	}
+	if err := createPersonalWorkspace(tx, user); err != nil {
+		tx.Rollback()
+		log.Printf("个人工作区创建失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "用户创建失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
 		log.Printf("用户创建失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "用户创建失败"})
 		return
@@ -151,18 +153,9 @@ func Login(c *gin.Context) {
 
 	log.Printf("密码验证成功为用户: %s", user.Username)
 
-	// 创建JWT Token
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &Claims{
-		UserID: user.ID,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
-		},
-	}
-
+	// 签发访问令牌和刷新令牌
 	log.Printf("开始生成JWT令牌为用户ID: %d", user.ID)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtKey)
+	pair, _, err := issueTokenPair(db, user.ID)
 	if err != nil {
 		log.Printf("JWT令牌生成失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法生成token"})
@@ -172,7 +165,9 @@ func Login(c *gin.Context) {
 	log.Printf("JWT令牌生成成功为用户: %s", user.Username)
 
 	c.JSON(http.StatusOK, gin.H{
-		"token": tokenString,
+		"token":        pair.AccessToken,
+		"refreshToken": pair.RefreshToken,
+		"expiresIn":    pair.ExpiresIn,
 		"user": gin.H{
 			"id":       user.ID,
 			"username": user.Username,
@@ -199,9 +194,7 @@ func GetUserInfo(c *gin.Context) {
 	// 构建头像URL
 	avatarUrl := ""
 	if user.AvatarPath != "" {
-		// 获取MinIO配置
-		minioConfig := config.GetMinioConfig()
-		avatarUrl = fmt.Sprintf("http://%s/%s/%s", minioConfig.Endpoint, minioConfig.Bucket, user.AvatarPath)
+		avatarUrl = presignedURL(user.AvatarPath, "")
 	}
 
 	// 返回用户信息，不包含敏感信息
@@ -291,7 +284,7 @@ func UploadAvatar(c *gin.Context) {
 	}
 
 	// 构建头像URL
-	avatarUrl := fmt.Sprintf("http://%s/%s/%s", minioConfig.Endpoint, minioConfig.Bucket, avatarFileName)
+	avatarUrl := presignedURL(avatarFileName, "")
 
 	// 返回成功响应
 	c.JSON(http.StatusOK, gin.H{
@@ -319,10 +312,28 @@ func GetAvatar(c *gin.Context) {
 	// 构建头像URL
 	avatarUrl := ""
 	if user.AvatarPath != "" {
-		// 获取MinIO配置
-		minioConfig := config.GetMinioConfig()
-		avatarUrl = fmt.Sprintf("http://%s/%s/%s", minioConfig.Endpoint, minioConfig.Bucket, user.AvatarPath)
+		avatarUrl = presignedURL(user.AvatarPath, "")
 	}
 
 	c.JSON(http.StatusOK, gin.H{"avatarUrl": avatarUrl})
 }
+
+// loadUserSummaries 按ID批量加载用户简要信息，已注销的用户同样返回用户名
+func loadUserSummaries(ids []uint) (map[uint]models.UserSummary, error) {
+	summaries := make(map[uint]models.UserSummary, len(ids))
+	if len(ids) == 0 {
+		return summaries, nil
+	}
+	for _, id := range ids {
+		summaries[id] = models.UserSummary{ID: id}
+	}
+
+	var users []models.User
+	if err := db.Unscoped().Select("id, username").Where("id IN (?)", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		summaries[user.ID] = models.UserSummary{ID: user.ID, Username: user.Username}
+	}
+	return summaries, nil
+}
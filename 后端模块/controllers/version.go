@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/models"
+)
+
+// bumpVersion 版本号自增表达式，修改任务的语句都应同时更新版本号
+var bumpVersion = gorm.Expr("version + 1")
+
+// errTaskModified 按版本号更新任务时，任务已被其他请求修改
+var errTaskModified = errors.New("任务已被修改")
+
+// taskETag 根据任务版本号生成ETag
+func taskETag(task models.Task) string {
+	return `"` + strconv.Itoa(task.Version) + `"`
+}
+
+// requestedTaskVersion 获取客户端持有的任务版本号
+// 依次读取If-Match请求头、请求体中的version、查询参数version
+// If-Match为*时不校验版本，返回nil；缺少版本号或格式无效时已写入错误响应
+func requestedTaskVersion(c *gin.Context, bodyVersion *int) (*int, bool) {
+	if ifMatch := strings.TrimSpace(c.GetHeader("If-Match")); ifMatch != "" {
+		if ifMatch == "*" {
+			return nil, true
+		}
+		value := strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+		version, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的If-Match请求头"})
+			return nil, false
+		}
+		return &version, true
+	}
+
+	if bodyVersion != nil {
+		return bodyVersion, true
+	}
+
+	if value := c.Query("version"); value != "" {
+		version, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的版本号"})
+			return nil, false
+		}
+		return &version, true
+	}
+
+	c.JSON(http.StatusPreconditionRequired, gin.H{"error": "缺少版本号，请通过If-Match请求头或version字段提供"})
+	return nil, false
+}
+
+// respondTaskConflict 版本号不一致时返回412，并附带任务的当前状态
+func respondTaskConflict(c *gin.Context, taskID uint) {
+	var task models.Task
+	if db.First(&task, taskID).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在或无权限"})
+		return
+	}
+
+	c.Header("ETag", taskETag(task))
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error":   "任务已被修改，请刷新后重试",
+		"current": buildTaskResponse(task),
+	})
+}
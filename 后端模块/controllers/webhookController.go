@@ -0,0 +1,681 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/config"
+	"taskmanager/models"
+	"taskmanager/webhook"
+)
+
+// 每个用户在一个工作区中最多创建的Webhook数
+const maxWebhooks = 10
+
+// 签名密钥的长度限制
+const (
+	minWebhookSecret = 16
+	maxWebhookSecret = 100
+)
+
+// 测试事件的类型，只由发送测试事件产生，不需要订阅
+const webhookTestEvent = "webhook.test"
+
+// 每轮最多处理的Webhook数和每个Webhook最多发送的投递数，其余的留到下一轮
+const (
+	webhookBatchSize     = 100
+	webhookHookBatchSize = 10
+)
+
+// 同时发送的Webhook数，同一个Webhook的投递按顺序发送
+const webhookWorkers = 8
+
+// 等待写入投递的事件数，超出时在请求中直接写入
+const webhookQueueSize = 1024
+
+// 重试间隔的上限
+const webhookMaxBackoff = time.Hour
+
+// 已结束的投递日志保留时间，过期后由后台清理
+const webhookLogRetention = 30 * 24 * time.Hour
+
+// webhookEventTypes 可以订阅的事件类型，与实时推送的事件一致
+var webhookEventTypes = []string{streamTaskCreated, streamTaskUpdated, streamTaskDeleted, streamFileUploaded, streamFileDeleted}
+
+// Webhook的发送参数，由InitWebhooks设置
+var (
+	webhookSender       = &webhook.Sender{}
+	webhookMaxAttempts  = 1
+	webhookRetryBackoff = 30 * time.Second
+	webhookInterval     = 30 * time.Second
+	webhookAllowPrivate = false
+	webhookCleanedAt    time.Time
+	// 有新的投递时唤醒后台发送
+	webhookWake = make(chan struct{}, 1)
+	// 待写入投递的事件，由后台按顺序写入，不占用请求的处理时间
+	webhookEvents = make(chan webhookEvent, webhookQueueSize)
+)
+
+// WebhookRequest 创建或更新Webhook的请求结构，更新时为空的字段保持不变
+type WebhookRequest struct {
+	URL          string   `json:"url"`          // 接收事件的地址，http或https
+	Events       []string `json:"events"`       // 订阅的事件类型
+	Active       *bool    `json:"active"`       // 是否启用，创建时默认启用
+	Secret       string   `json:"secret"`       // 签名密钥，创建时为空则自动生成
+	RotateSecret bool     `json:"rotateSecret"` // 更新时重新生成签名密钥
+}
+
+// webhookPayload 投递的请求体
+type webhookPayload struct {
+	Event       string      `json:"event"`
+	WorkspaceID uint        `json:"workspaceId"`
+	OccurredAt  time.Time   `json:"occurredAt"`
+	Data        interface{} `json:"data"`
+}
+
+// webhookEvent 等待写入投递的事件
+type webhookEvent struct {
+	Type        string
+	WorkspaceID uint
+	UserIDs     []uint
+	Data        interface{}
+	OccurredAt  time.Time
+}
+
+// InitWebhooks 根据配置启动Webhook的后台发送
+// 有新的投递时立即发送，失败的投递按退避时间重试
+func InitWebhooks(cfg config.WebhookConfig) {
+	webhookSender = &webhook.Sender{Client: webhook.NewClient(cfg.Timeout, cfg.AllowPrivate)}
+	webhookAllowPrivate = cfg.AllowPrivate
+	webhookMaxAttempts = cfg.MaxAttempts
+	if webhookMaxAttempts == 0 {
+		webhookMaxAttempts = 1
+	}
+	webhookRetryBackoff = cfg.RetryBackoff
+	webhookInterval = cfg.Interval
+
+	go func() {
+		for event := range webhookEvents {
+			enqueueWebhooks(event)
+		}
+	}()
+	go func() {
+		for {
+			timer := time.NewTimer(sendDueWebhooks(time.Now()))
+			select {
+			case <-timer.C:
+			case <-webhookWake:
+				timer.Stop()
+			}
+		}
+	}()
+}
+
+// GetWebhooks 获取当前用户在当前工作区的Webhook
+func GetWebhooks(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var hooks []models.Webhook
+	if err := db.Where("user_id = ? AND workspace_id = ?", userID, currentWorkspace(c)).
+		Order("id ASC").Find(&hooks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取Webhook失败"})
+		return
+	}
+
+	response := make([]models.WebhookResponse, len(hooks))
+	for i, hook := range hooks {
+		response[i] = toWebhookResponse(hook, false)
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": response})
+}
+
+// CreateWebhook 创建Webhook，响应中的签名密钥只返回这一次
+func CreateWebhook(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var hookReq WebhookRequest
+	if err := c.ShouldBindJSON(&hookReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	hook := models.Webhook{UserID: userID.(uint), WorkspaceID: currentWorkspace(c), Active: true}
+	if hookReq.Active != nil {
+		hook.Active = *hookReq.Active
+	}
+	if hookReq.URL == "" || len(hookReq.Events) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "地址和订阅的事件不能为空"})
+		return
+	}
+	if err := applyWebhookRequest(&hook, hookReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if hook.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建Webhook失败"})
+			return
+		}
+		hook.Secret = secret
+	}
+
+	var count int
+	if err := db.Model(&models.Webhook{}).Where("user_id = ? AND workspace_id = ?", hook.UserID, hook.WorkspaceID).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建Webhook失败"})
+		return
+	}
+	if count >= maxWebhooks {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("每个工作区最多创建%d个Webhook", maxWebhooks)})
+		return
+	}
+
+	if err := db.Create(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建Webhook失败"})
+		return
+	}
+	c.JSON(http.StatusOK, toWebhookResponse(hook, true))
+}
+
+// UpdateWebhook 更新Webhook的地址、订阅的事件、启用状态或签名密钥
+// 停用后尚未发送的投递不再发送；更换密钥后响应中返回新的密钥
+func UpdateWebhook(c *gin.Context) {
+	hook, ok := findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	var hookReq WebhookRequest
+	if err := c.ShouldBindJSON(&hookReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if hookReq.Active != nil {
+		hook.Active = *hookReq.Active
+	}
+	oldSecret := hook.Secret
+	if err := applyWebhookRequest(&hook, hookReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if hookReq.RotateSecret {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新Webhook失败"})
+			return
+		}
+		hook.Secret = secret
+	}
+
+	// 使用Save以便写入Active为false
+	if err := db.Save(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新Webhook失败"})
+		return
+	}
+	c.JSON(http.StatusOK, toWebhookResponse(hook, hook.Secret != oldSecret))
+}
+
+// DeleteWebhook 删除Webhook及其投递日志
+func DeleteWebhook(c *gin.Context) {
+	hook, ok := findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	tx := db.Begin()
+	if err := tx.Where("webhook_id = ?", hook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除Webhook失败"})
+		return
+	}
+	if err := tx.Delete(&hook).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除Webhook失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除Webhook失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook已删除"})
+}
+
+// TestWebhook 立即向Webhook发送一个测试事件并返回发送结果
+// 停用的Webhook也可以测试；测试事件失败后不会重试，结果记录在投递日志中
+func TestWebhook(c *gin.Context) {
+	hook, ok := findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	payload, err := json.Marshal(webhookPayload{
+		Event:       webhookTestEvent,
+		WorkspaceID: hook.WorkspaceID,
+		OccurredAt:  now,
+		Data:        gin.H{"webhookId": hook.ID, "message": "这是一个测试事件"},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发送测试事件失败"})
+		return
+	}
+	// 先记录再发送，发送期间后台不会处理该投递；进程中途退出时按普通投递在稍后重试
+	delivery := models.WebhookDelivery{
+		WebhookID:     hook.ID,
+		Event:         webhookTestEvent,
+		Payload:       string(payload),
+		Status:        models.DeliveryPending,
+		NextAttemptAt: now.Add(webhookMaxBackoff),
+	}
+	if err := db.Create(&delivery).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发送测试事件失败"})
+		return
+	}
+
+	delivery = sendWebhookDelivery(hook, delivery, false)
+	c.JSON(http.StatusOK, toWebhookDeliveryResponse(delivery))
+}
+
+// GetWebhookDeliveries 获取Webhook的投递日志，按时间倒序，可以按status筛选
+func GetWebhookDeliveries(c *gin.Context) {
+	hook, ok := findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	// 解析分页参数
+	pageSize, err := parsePageSize(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := parsePageNumber(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", hook.ID)
+	switch status := c.Query("status"); status {
+	case "":
+	case models.DeliveryPending, models.DeliveryDelivered, models.DeliveryFailed:
+		query = query.Where("status = ?", status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的status，可选值为: pending, delivered, failed"})
+		return
+	}
+	var total int
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取投递日志失败"})
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取投递日志失败"})
+		return
+	}
+
+	items := make([]models.WebhookDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		items[i] = toWebhookDeliveryResponse(delivery)
+	}
+	c.JSON(http.StatusOK, models.WebhookDeliveryListResponse{
+		Items:    items,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// findOwnWebhook 按URL中的ID查找当前用户在当前工作区的Webhook，返回false时已写入错误响应
+func findOwnWebhook(c *gin.Context) (models.Webhook, bool) {
+	var hook models.Webhook
+
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return hook, false
+	}
+
+	// 获取Webhook ID
+	hookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的Webhook ID"})
+		return hook, false
+	}
+
+	if db.Where("id = ? AND user_id = ? AND workspace_id = ?", hookID, userID, currentWorkspace(c)).
+		First(&hook).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook不存在"})
+		return hook, false
+	}
+	return hook, true
+}
+
+// applyWebhookRequest 校验请求中不为空的地址、事件和密钥并写入Webhook
+func applyWebhookRequest(hook *models.Webhook, hookReq WebhookRequest) error {
+	if hookReq.URL != "" {
+		target := strings.TrimSpace(hookReq.URL)
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(target) > 500 {
+			return fmt.Errorf("无效的地址，应为不超过500个字符的http或https地址")
+		}
+		if err := webhook.CheckHost(u.Hostname(), webhookAllowPrivate); err != nil {
+			return err
+		}
+		hook.URL = target
+	}
+
+	if len(hookReq.Events) > 0 {
+		seen := make(map[string]bool, len(hookReq.Events))
+		var events []string
+		for _, event := range hookReq.Events {
+			if !isWebhookEvent(event) {
+				return fmt.Errorf("无效的事件类型: %s，可选值为: %s", event, strings.Join(webhookEventTypes, ", "))
+			}
+			if !seen[event] {
+				seen[event] = true
+				events = append(events, event)
+			}
+		}
+		hook.Events = strings.Join(events, ",")
+	}
+
+	if hookReq.Secret != "" {
+		if len(hookReq.Secret) < minWebhookSecret || len(hookReq.Secret) > maxWebhookSecret {
+			return fmt.Errorf("签名密钥长度应为%d-%d个字符", minWebhookSecret, maxWebhookSecret)
+		}
+		hook.Secret = hookReq.Secret
+	}
+	return nil
+}
+
+// isWebhookEvent 判断是否为可以订阅的事件类型
+func isWebhookEvent(event string) bool {
+	for _, eventType := range webhookEventTypes {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// subscribes 判断Webhook是否订阅了该事件
+func subscribes(hook models.Webhook, event string) bool {
+	for _, subscribed := range strings.Split(hook.Events, ",") {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// generateWebhookSecret 生成32字节随机签名密钥
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// toWebhookResponse 转换为响应模型，withSecret为true时包含签名密钥
+func toWebhookResponse(hook models.Webhook, withSecret bool) models.WebhookResponse {
+	response := models.WebhookResponse{
+		ID:        hook.ID,
+		URL:       hook.URL,
+		Events:    strings.Split(hook.Events, ","),
+		Active:    hook.Active,
+		CreatedAt: hook.CreatedAt,
+		UpdatedAt: hook.UpdatedAt,
+	}
+	if withSecret {
+		response.Secret = hook.Secret
+	}
+	return response
+}
+
+// toWebhookDeliveryResponse 转换为投递日志响应模型
+func toWebhookDeliveryResponse(delivery models.WebhookDelivery) models.WebhookDeliveryResponse {
+	response := models.WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		Event:          delivery.Event,
+		Payload:        json.RawMessage(delivery.Payload),
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		LastError:      delivery.LastError,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == models.DeliveryPending {
+		next := delivery.NextAttemptAt
+		response.NextAttemptAt = &next
+	}
+	return response
+}
+
+// queueWebhookEvent 将事件交给后台写入投递；队列已满时直接写入，不丢弃事件
+func queueWebhookEvent(event webhookEvent) {
+	select {
+	case webhookEvents <- event:
+	default:
+		enqueueWebhooks(event)
+	}
+}
+
+// enqueueWebhooks 为接收者中订阅了该事件的Webhook创建投递，并唤醒后台发送
+func enqueueWebhooks(event webhookEvent) {
+	if len(event.UserIDs) == 0 {
+		return
+	}
+	var hooks []models.Webhook
+	if err := db.Where("workspace_id = ? AND user_id IN (?) AND active = ?", event.WorkspaceID, event.UserIDs, true).
+		Find(&hooks).Error; err != nil {
+		log.Printf("查找订阅事件 %s 的Webhook失败: %v", event.Type, err)
+		return
+	}
+
+	now := time.Now()
+	var payload []byte
+	queued := false
+	for _, hook := range hooks {
+		if !subscribes(hook, event.Type) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(webhookPayload{Event: event.Type, WorkspaceID: event.WorkspaceID, OccurredAt: event.OccurredAt, Data: event.Data}); err != nil {
+				log.Printf("序列化Webhook事件失败: %v", err)
+				return
+			}
+		}
+		if err := db.Create(&models.WebhookDelivery{
+			WebhookID:     hook.ID,
+			Event:         event.Type,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+		}).Error; err != nil {
+			log.Printf("创建Webhook %d 的投递失败: %v", hook.ID, err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		select {
+		case webhookWake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// sendDueWebhooks 发送到期的投递并清理过期的投递日志，返回到下一次发送需要等待的时间
+func sendDueWebhooks(now time.Time) time.Duration {
+	if now.Sub(webhookCleanedAt) >= time.Hour {
+		webhookCleanedAt = now
+		if err := db.Where("status <> ? AND updated_at < ?", models.DeliveryPending, now.Add(-webhookLogRetention)).
+			Delete(&models.WebhookDelivery{}).Error; err != nil {
+			log.Printf("清理Webhook投递日志失败: %v", err)
+		}
+	}
+
+	// 按最早到期的投递排序，取有到期投递的Webhook
+	var hookIDs []uint
+	if err := db.Model(&models.WebhookDelivery{}).
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Group("webhook_id").Order("MIN(next_attempt_at) ASC").
+		Limit(webhookBatchSize).
+		Pluck("webhook_id", &hookIDs).Error; err != nil {
+		log.Printf("查找待发送的Webhook投递失败: %v", err)
+		return webhookInterval
+	}
+
+	// 不同Webhook并发发送，一个Webhook响应慢不会拖住其他Webhook
+	ids := make(chan uint)
+	var wg sync.WaitGroup
+	for i := 0; i < webhookWorkers && i < len(hookIDs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				sendHookDeliveries(id, now)
+			}
+		}()
+	}
+	for _, id := range hookIDs {
+		ids <- id
+	}
+	close(ids)
+	wg.Wait()
+
+	// 等到最早的一个待发送投递，至少等待1秒，最多等待检查间隔
+	wait := webhookInterval
+	var next models.WebhookDelivery
+	if !db.Where("status = ?", models.DeliveryPending).Order("next_attempt_at ASC").First(&next).RecordNotFound() {
+		if until := time.Until(next.NextAttemptAt); until < wait {
+			wait = until
+		}
+	}
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait
+}
+
+// sendHookDeliveries 按顺序发送一个Webhook到期的投递
+// 每轮最多发送webhookHookBatchSize个，发送失败后其余投递留到下一轮，避免对不可用的地址逐个等待超时
+func sendHookDeliveries(hookID uint, now time.Time) {
+	var hook models.Webhook
+	if err := db.First(&hook, hookID).Error; err != nil {
+		log.Printf("加载Webhook %d 失败: %v", hookID, err)
+		return
+	}
+	var deliveries []models.WebhookDelivery
+	if err := db.Where("webhook_id = ? AND status = ? AND next_attempt_at <= ?", hookID, models.DeliveryPending, now).
+		Order("next_attempt_at ASC").Order("id ASC").
+		Limit(webhookHookBatchSize).
+		Find(&deliveries).Error; err != nil {
+		log.Printf("查找Webhook %d 待发送的投递失败: %v", hookID, err)
+		return
+	}
+	for _, delivery := range deliveries {
+		if sent := sendWebhookDelivery(hook, delivery, true); hook.Active && sent.Status != models.DeliveryDelivered {
+			return
+		}
+	}
+}
+
+// sendWebhookDelivery 发送一次投递并记录结果，返回更新后的投递
+// Webhook已停用时不再发送；retry为false时失败后不再重试
+func sendWebhookDelivery(hook models.Webhook, delivery models.WebhookDelivery, retry bool) models.WebhookDelivery {
+	now := time.Now()
+	var updates map[string]interface{}
+	if !hook.Active && delivery.Event != webhookTestEvent {
+		updates = map[string]interface{}{
+			"status":     models.DeliveryFailed,
+			"last_error": "Webhook已停用",
+		}
+	} else {
+		responseStatus, err := webhookSender.Send(hook.URL, hook.Secret, webhook.Delivery{
+			ID:        strconv.FormatUint(uint64(delivery.ID), 10),
+			Event:     delivery.Event,
+			Payload:   []byte(delivery.Payload),
+			Timestamp: now,
+		})
+		updates = webhookAttemptUpdates(delivery, responseStatus, err, now, retry)
+		if err != nil {
+			log.Printf("发送Webhook %d 的投递 %d 失败（第%d次）: %v", hook.ID, delivery.ID, delivery.Attempts+1, err)
+		}
+	}
+
+	if err := db.Model(&delivery).Updates(updates).Error; err != nil {
+		log.Printf("记录Webhook投递 %d 的状态失败: %v", delivery.ID, err)
+	}
+	return delivery
+}
+
+// webhookAttemptUpdates 根据一次发送的结果计算投递需要更新的字段
+// 失败后按重试间隔翻倍退避，超过最大尝试次数或不重试时标记为失败
+func webhookAttemptUpdates(delivery models.WebhookDelivery, responseStatus int, sendErr error, now time.Time, retry bool) map[string]interface{} {
+	attempts := delivery.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":        attempts,
+		"response_status": responseStatus,
+	}
+	if sendErr == nil {
+		updates["status"] = models.DeliveryDelivered
+		updates["delivered_at"] = now
+		updates["last_error"] = ""
+		return updates
+	}
+
+	updates["last_error"] = excerpt(sendErr.Error(), 255)
+	if !retry || attempts >= webhookMaxAttempts {
+		updates["status"] = models.DeliveryFailed
+		return updates
+	}
+	backoff := webhookRetryBackoff << uint(attempts-1)
+	if backoff > webhookMaxBackoff || backoff <= 0 {
+		backoff = webhookMaxBackoff
+	}
+	updates["next_attempt_at"] = now.Add(backoff)
+	return updates
+}
+
+// deleteUserWebhooks 删除用户在工作区中的Webhook及其投递日志，用于成员离开工作区
+func deleteUserWebhooks(tx *gorm.DB, userID, workspaceID uint) error {
+	hooks := tx.Model(&models.Webhook{}).Select("id").Where("user_id = ? AND workspace_id = ?", userID, workspaceID).SubQuery()
+	if err := tx.Where("webhook_id IN ?", hooks).Delete(&models.WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ? AND workspace_id = ?", userID, workspaceID).Delete(&models.Webhook{}).Error
+}
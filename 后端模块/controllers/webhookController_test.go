@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"taskmanager/models"
+)
+
+func TestWebhookAttemptUpdates(t *testing.T) {
+	savedAttempts, savedBackoff := webhookMaxAttempts, webhookRetryBackoff
+	webhookMaxAttempts, webhookRetryBackoff = 4, 30*time.Second
+	defer func() { webhookMaxAttempts, webhookRetryBackoff = savedAttempts, savedBackoff }()
+
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	sendErr := errors.New("webhook返回状态码 500")
+	tests := []struct {
+		name        string
+		attempts    int
+		status      int
+		err         error
+		retry       bool
+		wantStatus  string
+		wantBackoff time.Duration // 为0表示不再重试
+	}{
+		{"首次成功", 0, 200, nil, true, models.DeliveryDelivered, 0},
+		{"重试后成功", 2, 204, nil, true, models.DeliveryDelivered, 0},
+		{"不重试时成功", 0, 200, nil, false, models.DeliveryDelivered, 0},
+		{"首次失败", 0, 500, sendErr, true, models.DeliveryPending, 30 * time.Second},
+		{"第二次失败翻倍", 1, 500, sendErr, true, models.DeliveryPending, time.Minute},
+		{"第三次失败", 2, 0, sendErr, true, models.DeliveryPending, 2 * time.Minute},
+		{"达到最大次数", 3, 500, sendErr, true, models.DeliveryFailed, 0},
+		{"超过最大次数", 7, 500, sendErr, true, models.DeliveryFailed, 0},
+		{"不重试时失败", 0, 500, sendErr, false, models.DeliveryFailed, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates := webhookAttemptUpdates(models.WebhookDelivery{Attempts: tt.attempts}, tt.status, tt.err, now, tt.retry)
+			if updates["attempts"] != tt.attempts+1 {
+				t.Errorf("attempts = %v, 期望 %d", updates["attempts"], tt.attempts+1)
+			}
+			if updates["response_status"] != tt.status {
+				t.Errorf("response_status = %v, 期望 %d", updates["response_status"], tt.status)
+			}
+			status, ok := updates["status"]
+			if !ok {
+				status = models.DeliveryPending
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status = %v, 期望 %s", status, tt.wantStatus)
+			}
+			next, ok := updates["next_attempt_at"]
+			if tt.wantBackoff == 0 {
+				if ok {
+					t.Errorf("不应再重试，next_attempt_at = %v", next)
+				}
+			} else if next != now.Add(tt.wantBackoff) {
+				t.Errorf("next_attempt_at = %v, 期望 %v", next, now.Add(tt.wantBackoff))
+			}
+			if tt.err == nil {
+				if updates["delivered_at"] != now || updates["last_error"] != "" {
+					t.Errorf("成功后 delivered_at = %v, last_error = %q", updates["delivered_at"], updates["last_error"])
+				}
+			} else if updates["last_error"] != tt.err.Error() {
+				t.Errorf("last_error = %v", updates["last_error"])
+			}
+		})
+	}
+}
+
+func TestWebhookAttemptUpdatesMaxBackoff(t *testing.T) {
+	savedAttempts, savedBackoff := webhookMaxAttempts, webhookRetryBackoff
+	webhookMaxAttempts, webhookRetryBackoff = 100, 30*time.Second
+	defer func() { webhookMaxAttempts, webhookRetryBackoff = savedAttempts, savedBackoff }()
+
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	for _, attempts := range []int{7, 8, 30, 80} {
+		updates := webhookAttemptUpdates(models.WebhookDelivery{Attempts: attempts}, 0, errors.New("超时"), now, true)
+		if updates["next_attempt_at"] != now.Add(webhookMaxBackoff) {
+			t.Errorf("第%d次失败: next_attempt_at = %v, 期望不超过 %v", attempts+1, updates["next_attempt_at"], webhookMaxBackoff)
+		}
+	}
+}
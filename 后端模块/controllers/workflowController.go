@@ -0,0 +1,663 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/models"
+)
+
+// 看板列内相邻任务rank的间隔，插入位置没有空隙时重新编号整列
+const rankGap int64 = 1024
+
+// 状态标识格式，小写字母开头，只包含小写字母、数字和下划线
+var statusKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// defaultWorkflowStates 内置的默认工作流，用户未自定义默认工作流时使用
+// 升级前的任务按completed归入todo或done
+var defaultWorkflowStates = []models.WorkflowState{
+	{Key: "todo", Name: "待办", Color: "#909399", Category: models.StatusCategoryTodo},
+	{Key: "in_progress", Name: "进行中", Color: "#409EFF", Category: models.StatusCategoryInProgress},
+	{Key: "blocked", Name: "已阻塞", Color: "#F56C6C", Category: models.StatusCategoryInProgress},
+	{Key: "in_review", Name: "审核中", Color: "#E6A23C", Category: models.StatusCategoryInProgress},
+	{Key: "done", Name: "已完成", Color: "#67C23A", Category: models.StatusCategoryDone},
+}
+
+// 各状态分类的默认颜色
+var statusCategoryColors = map[string]string{
+	models.StatusCategoryTodo:       "#909399",
+	models.StatusCategoryInProgress: "#409EFF",
+	models.StatusCategoryDone:       "#67C23A",
+}
+
+// workflow 任务所使用的工作流
+type workflow struct {
+	projectID *uint
+	custom    bool // 是否为项目自定义的工作流
+	states    []models.WorkflowState
+}
+
+// state 按标识查找状态
+func (w *workflow) state(key string) (models.WorkflowState, bool) {
+	for _, state := range w.states {
+		if state.Key == key {
+			return state, true
+		}
+	}
+	return models.WorkflowState{}, false
+}
+
+// initialState 返回第一个已完成或未完成的状态，用于新任务和无法保留原状态的任务
+// 工作流保存时已保证两类状态都存在
+func (w *workflow) initialState(done bool) models.WorkflowState {
+	for _, state := range w.states {
+		if state.Done() == done {
+			return state
+		}
+	}
+	return w.states[0]
+}
+
+// reachableState 返回从当前状态可以流转到的第一个已完成或未完成的状态
+func (w *workflow) reachableState(from models.WorkflowState, done bool) (models.WorkflowState, bool) {
+	for _, state := range w.states {
+		if state.Key != from.Key && state.Done() == done && from.CanTransition(state.Key) {
+			return state, true
+		}
+	}
+	return models.WorkflowState{}, false
+}
+
+// loadWorkflow 加载项目使用的工作流，projectID为空或项目没有自定义工作流时返回默认工作流
+// 在事务中调用时传入事务，以读取事务内修改后的状态
+func loadWorkflow(tx *gorm.DB, userID uint, projectID *uint) (*workflow, error) {
+	if projectID != nil {
+		var states []models.WorkflowState
+		if err := tx.Where("user_id = ? AND project_id = ?", userID, *projectID).
+			Order("sort_order ASC").Order("id ASC").Find(&states).Error; err != nil {
+			return nil, err
+		}
+		if len(states) > 0 {
+			return &workflow{projectID: projectID, custom: true, states: states}, nil
+		}
+	}
+
+	var states []models.WorkflowState
+	if err := tx.Where("user_id = ? AND project_id IS NULL", userID).
+		Order("sort_order ASC").Order("id ASC").Find(&states).Error; err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		states = defaultWorkflowStates
+	}
+	return &workflow{projectID: projectID, states: states}, nil
+}
+
+// defaultWorkflowScope 限定为使用默认工作流的任务，即未归入项目或所在项目没有自定义工作流的任务
+func defaultWorkflowScope(tx *gorm.DB, query *gorm.DB, userID uint) *gorm.DB {
+	custom := tx.Model(&models.WorkflowState{}).
+		Select("DISTINCT project_id").
+		Where("user_id = ? AND project_id IS NOT NULL", userID)
+	return query.Where("project_id IS NULL OR project_id NOT IN ?", custom.SubQuery())
+}
+
+// InitWorkflow 为升级前创建的任务补齐工作流状态和看板排序
+// 旧任务按completed归入默认工作流的todo或done列，列内按创建顺序排列
+func InitWorkflow() {
+	if err := db.Unscoped().Model(&models.Task{}).Where("status = '' OR status IS NULL").UpdateColumns(map[string]interface{}{
+		"status":     gorm.Expr("CASE WHEN completed THEN 'done' ELSE 'todo' END"),
+		"board_rank": gorm.Expr("id * ?", rankGap),
+	}).Error; err != nil {
+		log.Printf("补齐任务状态失败: %v", err)
+	}
+}
+
+// GetWorkflow 获取工作流，指定projectId时返回该项目使用的工作流
+func GetWorkflow(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	projectID, ok := workflowProject(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	wf, err := loadWorkflow(db, userID.(uint), projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取工作流失败"})
+		return
+	}
+	c.JSON(http.StatusOK, toWorkflowResponse(wf))
+}
+
+// WorkflowStateRequest 工作流状态请求结构
+type WorkflowStateRequest struct {
+	Key         string   `json:"key"`         // 状态标识，必填，在工作流内唯一
+	Name        string   `json:"name"`        // 显示名称，必填
+	Color       string   `json:"color"`       // 颜色，可选，默认按分类取色
+	Category    string   `json:"category"`    // 状态分类，可选值为"todo", "in_progress", "done"
+	Transitions []string `json:"transitions"` // 允许流转到的状态，为空表示不限制
+}
+
+// WorkflowRequest 保存工作流的请求结构
+type WorkflowRequest struct {
+	ProjectID *uint                  `json:"projectId"` // 项目ID，为空表示修改默认工作流
+	States    []WorkflowStateRequest `json:"states"`    // 按列顺序排列的全部状态，为空表示恢复默认
+}
+
+// UpdateWorkflow 保存工作流，整体替换原有状态
+// 状态被删除的任务改为同一完成状态的第一个状态，状态分类变化的任务同步completed
+func UpdateWorkflow(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var workflowReq WorkflowRequest
+	if err := c.ShouldBindJSON(&workflowReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的工作流数据"})
+		return
+	}
+	if workflowReq.ProjectID != nil && !ownsProject(userID, currentWorkspace(c), *workflowReq.ProjectID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "项目不存在或无权限"})
+		return
+	}
+
+	states, ok := buildWorkflowStates(c, userID.(uint), workflowReq)
+	if !ok {
+		return
+	}
+
+	tx := db.Begin()
+	scope := tx.Where("user_id = ?", userID)
+	if workflowReq.ProjectID != nil {
+		scope = scope.Where("project_id = ?", *workflowReq.ProjectID)
+	} else {
+		scope = scope.Where("project_id IS NULL")
+	}
+	if err := scope.Delete(&models.WorkflowState{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存工作流失败"})
+		return
+	}
+	for i := range states {
+		if err := tx.Create(&states[i]).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存工作流失败"})
+			return
+		}
+	}
+
+	// 校正使用该工作流的任务，包括回收站中的任务
+	tasks := tx.Unscoped().Model(&models.Task{}).Where("user_id = ?", userID)
+	if workflowReq.ProjectID != nil {
+		tasks = tasks.Where("project_id = ?", *workflowReq.ProjectID)
+	} else {
+		tasks = defaultWorkflowScope(tx, tasks, userID.(uint))
+	}
+	var taskIDs []uint
+	if err := tasks.Pluck("id", &taskIDs).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存工作流失败"})
+		return
+	}
+	if err := syncTaskStatuses(tx, userID.(uint), taskIDs); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存工作流失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存工作流失败"})
+		return
+	}
+
+	wf, err := loadWorkflow(db, userID.(uint), workflowReq.ProjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取工作流失败"})
+		return
+	}
+	c.JSON(http.StatusOK, toWorkflowResponse(wf))
+}
+
+// buildWorkflowStates 校验请求中的状态并生成状态模型，校验失败时已写入错误响应
+func buildWorkflowStates(c *gin.Context, userID uint, workflowReq WorkflowRequest) ([]models.WorkflowState, bool) {
+	states := make([]models.WorkflowState, 0, len(workflowReq.States))
+	if len(workflowReq.States) == 0 {
+		return states, true
+	}
+
+	keys := make(map[string]bool, len(workflowReq.States))
+	hasDone, hasOpen := false, false
+	for _, stateReq := range workflowReq.States {
+		if !statusKeyPattern.MatchString(stateReq.Key) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的状态标识，只能包含小写字母、数字和下划线，且以字母开头"})
+			return nil, false
+		}
+		if keys[stateReq.Key] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "状态标识重复: " + stateReq.Key})
+			return nil, false
+		}
+		keys[stateReq.Key] = true
+
+		switch stateReq.Category {
+		case models.StatusCategoryTodo, models.StatusCategoryInProgress:
+			hasOpen = true
+		case models.StatusCategoryDone:
+			hasDone = true
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的状态分类，可选值为: todo, in_progress, done"})
+			return nil, false
+		}
+	}
+	if !hasDone || !hasOpen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "工作流至少需要一个已完成分类和一个未完成分类的状态"})
+		return nil, false
+	}
+
+	for i, stateReq := range workflowReq.States {
+		name := strings.TrimSpace(stateReq.Name)
+		if name == "" || utf8.RuneCountInString(name) > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "状态名称不能为空且不能超过50个字符"})
+			return nil, false
+		}
+
+		color := stateReq.Color
+		if color == "" {
+			color = statusCategoryColors[stateReq.Category]
+		} else if !tagColorPattern.MatchString(color) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的颜色，格式为 #RRGGBB"})
+			return nil, false
+		}
+
+		var transitions []string
+		seen := make(map[string]bool, len(stateReq.Transitions))
+		for _, key := range stateReq.Transitions {
+			if !keys[key] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "流转的目标状态不存在: " + key})
+				return nil, false
+			}
+			if !seen[key] && key != stateReq.Key {
+				seen[key] = true
+				transitions = append(transitions, key)
+			}
+		}
+
+		states = append(states, models.WorkflowState{
+			UserID:      userID,
+			ProjectID:   workflowReq.ProjectID,
+			Key:         stateReq.Key,
+			Name:        name,
+			Color:       color,
+			Category:    stateReq.Category,
+			SortOrder:   i,
+			Transitions: strings.Join(transitions, ","),
+		})
+	}
+	return states, true
+}
+
+// GetBoard 获取看板，按工作流状态分列返回任务，列内按rank排序
+// 未指定projectId时返回使用默认工作流的任务；支持priority、tag、tagMode筛选参数
+func GetBoard(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	// 每列最多返回的任务数
+	pageSize, err := parsePageSize(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var projectID *uint
+	query := db.Where("user_id = ? AND workspace_id = ?", userID, currentWorkspace(c))
+	if c.Query("projectId") == "none" {
+		query = query.Where("project_id IS NULL")
+	} else {
+		var ok bool
+		if projectID, ok = workflowProject(c, userID.(uint)); !ok {
+			return
+		}
+		if projectID != nil {
+			query = query.Where("project_id = ?", *projectID)
+		} else {
+			query = defaultWorkflowScope(db, query, userID.(uint))
+		}
+	}
+	query, ok := filterTasks(c, query)
+	if !ok {
+		return
+	}
+
+	wf, err := loadWorkflow(db, userID.(uint), projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取看板失败"})
+		return
+	}
+	result := models.BoardResponse{ProjectID: projectID, Custom: wf.custom, Columns: make([]models.BoardColumn, len(wf.states))}
+	columns := make([][]models.Task, len(wf.states))
+	totals := make([]int, len(wf.states))
+	var ids []uint
+	for i, state := range wf.states {
+		column := query.Model(&models.Task{}).Where("status = ?", state.Key)
+
+		if err := column.Count(&totals[i]).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取看板失败"})
+			return
+		}
+		if err := column.Order("board_rank ASC").Order("id ASC").Limit(pageSize).
+			Preload("Tags").Preload("Attachments").Find(&columns[i]).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取看板失败"})
+			return
+		}
+		ids = append(ids, taskIDs(columns[i])...)
+	}
+
+	// 只加载看板上任务的子树和依赖关系
+	tree, err := loadTaskTree(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取看板失败"})
+		return
+	}
+	for i, state := range wf.states {
+		tasks := columns[i]
+		responses := make([]models.TaskResponse, len(tasks))
+		for j, task := range tasks {
+			responses[j] = toTaskResponse(task)
+			tree.fill(&responses[j])
+		}
+		result.Columns[i] = models.BoardColumn{State: toWorkflowStateResponse(state), Tasks: responses, Total: totals[i]}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// MoveTaskStatusRequest 在看板中移动任务的请求结构
+type MoveTaskStatusRequest struct {
+	Status   string `json:"status" binding:"required"` // 目标状态
+	BeforeID *uint  `json:"beforeId"`                  // 放在该任务之前，优先于afterId
+	AfterID  *uint  `json:"afterId"`                   // 放在该任务之后，两者都为空时放到列末尾
+	Version  *int   `json:"version"`                   // 客户端持有的版本号，也可以通过If-Match请求头提供
+}
+
+// MoveTaskStatus 在看板中移动任务，可以改变状态和列内位置
+// 状态变化需符合工作流的流转规则，completed随目标状态的分类变化
+func MoveTaskStatus(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	task, ok := findTask(c, accessEdit)
+	if !ok {
+		return
+	}
+
+	var moveReq MoveTaskStatusRequest
+	if err := c.ShouldBindJSON(&moveReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	version, ok := requestedTaskVersion(c, moveReq.Version)
+	if !ok {
+		return
+	}
+	if version != nil && *version != task.Version {
+		respondTaskConflict(c, task.ID)
+		return
+	}
+
+	wf, err := loadWorkflow(db, task.UserID, task.ProjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移动任务失败"})
+		return
+	}
+	state, ok := wf.state(moveReq.Status)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务状态"})
+		return
+	}
+	if current, ok := wf.state(task.Status); ok && !current.CanTransition(state.Key) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不允许从「" + current.Name + "」流转到「" + state.Name + "」"})
+		return
+	}
+
+	// 参照任务必须在目标列中
+	anchorID := moveReq.BeforeID
+	if anchorID == nil {
+		anchorID = moveReq.AfterID
+	}
+	if anchorID != nil {
+		var count int
+		db.Model(&models.Task{}).Where("id = ? AND id <> ? AND user_id = ? AND status = ?", *anchorID, task.ID, task.UserID, state.Key).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "参照任务不在目标列中"})
+			return
+		}
+	}
+
+	completing := state.Done() && !task.Completed
+	if completing && !checkOpenBlockers(c, task) {
+		return
+	}
+
+	// 完成重复任务时在同一事务中生成下一次任务
+	var next *models.Task
+	tx := db.Begin()
+	rank, err := placeInColumn(tx, task.UserID, state.Key, task.ID, moveReq.BeforeID, moveReq.AfterID)
+	if err == nil {
+		err = applyTaskState(tx, &task, userID.(uint), state, rank)
+	}
+	if err == nil && completing {
+		next, err = spawnNextOccurrence(tx, &task, userID.(uint))
+	}
+	if err == nil {
+		err = tx.Commit().Error
+	} else {
+		tx.Rollback()
+	}
+	if err == errTaskModified || err == errOccurrenceSpawned {
+		respondTaskConflict(c, task.ID)
+		return
+	}
+	if err != nil {
+		log.Printf("移动任务 %d 失败: %v", task.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移动任务失败"})
+		return
+	}
+	publishTaskEvent(streamTaskUpdated, task)
+	if next != nil {
+		publishTaskEvent(streamTaskCreated, *next)
+	}
+
+	respondTask(c, task)
+}
+
+// placeInColumn 计算任务放入看板列中指定位置时的rank
+// beforeID、afterID表示放在该任务之前或之后，都为空或参照任务不在列中时放到列末尾
+// 相邻任务之间没有空隙时重新编号整列
+func placeInColumn(tx *gorm.DB, userID uint, status string, taskID uint, beforeID, afterID *uint) (int64, error) {
+	var column []models.Task
+	if err := tx.Select("id, board_rank").Where("user_id = ? AND status = ? AND id <> ?", userID, status, taskID).
+		Order("board_rank ASC").Order("id ASC").Find(&column).Error; err != nil {
+		return 0, err
+	}
+
+	pos := len(column)
+	for i, other := range column {
+		if beforeID != nil && other.ID == *beforeID {
+			pos = i
+			break
+		}
+		if beforeID == nil && afterID != nil && other.ID == *afterID {
+			pos = i + 1
+			break
+		}
+	}
+
+	switch {
+	case len(column) == 0:
+		return rankGap, nil
+	case pos == 0:
+		return column[0].Rank - rankGap, nil
+	case pos == len(column):
+		return column[pos-1].Rank + rankGap, nil
+	}
+	if low, high := column[pos-1].Rank, column[pos].Rank; high-low >= 2 {
+		return low + (high-low)/2, nil
+	}
+
+	for i, other := range column {
+		rank := int64(i+1) * rankGap
+		if i >= pos {
+			rank += rankGap
+		}
+		if rank == other.Rank {
+			continue
+		}
+		if err := tx.Model(&models.Task{}).Where("id = ?", other.ID).UpdateColumn("board_rank", rank).Error; err != nil {
+			return 0, err
+		}
+	}
+	return int64(pos+1) * rankGap, nil
+}
+
+// applyTaskState 将任务改为指定状态和rank，同步completed并写入变更记录
+// 按task中的版本号更新，任务已被其他请求修改时返回errTaskModified
+func applyTaskState(tx *gorm.DB, task *models.Task, actorID uint, state models.WorkflowState, rank int64) error {
+	before := *task
+	result := tx.Model(&models.Task{}).Where("id = ? AND version = ?", task.ID, task.Version).Updates(map[string]interface{}{
+		"status":     state.Key,
+		"completed":  state.Done(),
+		"board_rank": rank,
+		"version":    bumpVersion,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errTaskModified
+	}
+	task.Status = state.Key
+	task.Completed = state.Done()
+	task.Rank = rank
+	task.Version++
+
+	changes := diffTask(before, *task)
+	if len(changes) == 0 {
+		return nil
+	}
+	action := models.TaskEventUpdated
+	if task.Completed && !before.Completed {
+		action = models.TaskEventCompleted
+	} else if before.Completed && !task.Completed {
+		action = models.TaskEventReopened
+	}
+	return recordTaskEvent(tx, task.ID, actorID, action, changes)
+}
+
+// syncTaskStatuses 在任务改用其他工作流或工作流修改后校正任务状态
+// 状态在工作流中不存在时改为同一完成状态的第一个状态并放到列末尾，状态分类变化时同步completed
+func syncTaskStatuses(tx *gorm.DB, userID uint, taskIDs []uint) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+
+	var tasks []models.Task
+	if err := tx.Unscoped().Select("id, project_id, status, completed, board_rank, version").
+		Where("id IN (?)", taskIDs).Order("id ASC").Find(&tasks).Error; err != nil {
+		return err
+	}
+
+	workflows := make(map[uint]*workflow)
+	for i := range tasks {
+		task := &tasks[i]
+		var key uint
+		if task.ProjectID != nil {
+			key = *task.ProjectID
+		}
+		wf, ok := workflows[key]
+		if !ok {
+			var err error
+			if wf, err = loadWorkflow(tx, userID, task.ProjectID); err != nil {
+				return err
+			}
+			workflows[key] = wf
+		}
+
+		state, ok := wf.state(task.Status)
+		if ok && state.Done() == task.Completed {
+			continue
+		}
+		rank := task.Rank
+		if !ok {
+			state = wf.initialState(task.Completed)
+			var err error
+			if rank, err = placeInColumn(tx, userID, state.Key, task.ID, nil, nil); err != nil {
+				return err
+			}
+		}
+		if err := applyTaskState(tx.Unscoped(), task, userID, state, rank); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// workflowProject 解析projectId参数，为空表示默认工作流，失败时已写入错误响应
+func workflowProject(c *gin.Context, userID uint) (*uint, bool) {
+	value := c.Query("projectId")
+	if value == "" {
+		return nil, true
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的项目ID"})
+		return nil, false
+	}
+	projectID := uint(id)
+	if !ownsProject(userID, currentWorkspace(c), projectID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "项目不存在或无权限"})
+		return nil, false
+	}
+	return &projectID, true
+}
+
+// toWorkflowResponse 将工作流转换为响应模型
+func toWorkflowResponse(wf *workflow) models.WorkflowResponse {
+	states := make([]models.WorkflowStateResponse, len(wf.states))
+	for i, state := range wf.states {
+		states[i] = toWorkflowStateResponse(state)
+	}
+	return models.WorkflowResponse{ProjectID: wf.projectID, Custom: wf.custom, States: states}
+}
+
+// toWorkflowStateResponse 将工作流状态转换为响应模型
+func toWorkflowStateResponse(state models.WorkflowState) models.WorkflowStateResponse {
+	return models.WorkflowStateResponse{
+		Key:         state.Key,
+		Name:        state.Name,
+		Color:       state.Color,
+		Category:    state.Category,
+		Transitions: state.TransitionKeys(),
+	}
+}
@@ -0,0 +1,872 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/models"
+)
+
+// 邀请的有效期
+const invitationTTL = 7 * 24 * time.Hour
+
+// 角色级别，数值越大权限越高；只能管理级别比自己低的成员，授予的角色也必须比自己低
+var roleRanks = map[string]int{
+	models.RoleGuest:  1,
+	models.RoleMember: 2,
+	models.RoleAdmin:  3,
+	models.RoleOwner:  4,
+}
+
+// WorkspaceRequest 创建和更新工作区的请求结构
+type WorkspaceRequest struct {
+	Name string `json:"name"` // 工作区名称，必填
+}
+
+// InviteRequest 邀请成员的请求结构，username和email二选一
+type InviteRequest struct {
+	Username string `json:"username"` // 被邀请的用户名
+	Email    string `json:"email"`    // 被邀请的邮箱，可以是尚未注册的用户
+	Role     string `json:"role"`     // 接受后获得的角色，admin、member或guest，默认member
+}
+
+// InvitationReplyRequest 接受或拒绝邀请的请求结构，token和id二选一
+type InvitationReplyRequest struct {
+	Token string `json:"token"` // 邀请链接中的令牌
+	ID    uint   `json:"id"`    // 邀请ID，用于在站内接受发给自己的邀请
+}
+
+// MemberRequest 修改或移除成员的请求结构
+type MemberRequest struct {
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role"` // 新角色，修改角色时必填
+}
+
+// InitWorkspaces 为升级前注册的用户创建个人工作区，并将其任务、项目和文件归入个人工作区
+func InitWorkspaces() {
+	var users []models.User
+	if err := db.Where("id NOT IN ?", db.Model(&models.Workspace{}).Select("owner_id").Where("personal = ?", true).SubQuery()).
+		Find(&users).Error; err != nil {
+		log.Printf("查找没有个人工作区的用户失败: %v", err)
+		return
+	}
+	for _, user := range users {
+		tx := db.Begin()
+		if err := createPersonalWorkspace(tx, user); err != nil {
+			tx.Rollback()
+			log.Printf("为用户 %d 创建个人工作区失败: %v", user.ID, err)
+			continue
+		}
+		tx.Commit()
+	}
+
+	for _, table := range []string{"tasks", "projects", "files"} {
+		personal := gorm.Expr("(SELECT id FROM workspaces WHERE workspaces.owner_id = "+table+".user_id AND workspaces.personal = ? LIMIT 1)", true)
+		if err := db.Table(table).Where("workspace_id = ?", 0).UpdateColumn("workspace_id", personal).Error; err != nil {
+			log.Printf("补齐%s的工作区失败: %v", table, err)
+		}
+	}
+
+	// 共享和负责人只能是任务所属工作区的成员，升级前的共享对象以访客身份加入
+	for _, table := range []string{"task_shares", "task_assignees"} {
+		var rows []struct {
+			WorkspaceID uint
+			UserID      uint
+		}
+		if err := db.Table(table).
+			Select("DISTINCT tasks.workspace_id, " + table + ".user_id").
+			Joins("JOIN tasks ON tasks.id = " + table + ".task_id").
+			Where("NOT EXISTS (SELECT 1 FROM memberships WHERE memberships.workspace_id = tasks.workspace_id AND memberships.user_id = " + table + ".user_id)").
+			Scan(&rows).Error; err != nil {
+			log.Printf("查找%s中的非成员失败: %v", table, err)
+			continue
+		}
+		for _, row := range rows {
+			membership := models.Membership{WorkspaceID: row.WorkspaceID, UserID: row.UserID, Role: models.RoleGuest}
+			if err := db.Where(models.Membership{WorkspaceID: row.WorkspaceID, UserID: row.UserID}).FirstOrCreate(&membership).Error; err != nil {
+				log.Printf("添加工作区 %d 的访客 %d 失败: %v", row.WorkspaceID, row.UserID, err)
+			}
+		}
+	}
+}
+
+// createPersonalWorkspace 为用户创建个人工作区
+func createPersonalWorkspace(tx *gorm.DB, user models.User) error {
+	workspace := models.Workspace{Name: user.Username + "的工作区", OwnerID: user.ID, Personal: true}
+	if err := tx.Create(&workspace).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.Membership{WorkspaceID: workspace.ID, UserID: user.ID, Role: models.RoleOwner}).Error
+}
+
+// currentWorkspace 返回JWTAuth确定的当前工作区ID
+func currentWorkspace(c *gin.Context) uint {
+	value, _ := c.Get("workspaceId")
+	workspaceID, _ := value.(uint)
+	return workspaceID
+}
+
+// requireMember 访客不能创建任务、项目和文件，返回false时已写入错误响应
+func requireMember(c *gin.Context) bool {
+	if role, _ := c.Get("workspaceRole"); role == models.RoleGuest {
+		c.JSON(http.StatusForbidden, gin.H{"error": "访客没有此操作的权限"})
+		return false
+	}
+	return true
+}
+
+// isWorkspaceMember 判断用户是否为工作区成员
+func isWorkspaceMember(workspaceID, userID uint) bool {
+	var count int
+	db.Model(&models.Membership{}).Where("workspace_id = ? AND user_id = ?", workspaceID, userID).Count(&count)
+	return count > 0
+}
+
+// inWorkspace 判断任务是否属于工作区
+func inWorkspace(taskID, workspaceID uint) bool {
+	var count int
+	db.Model(&models.Task{}).Where("id = ? AND workspace_id = ?", taskID, workspaceID).Count(&count)
+	return count > 0
+}
+
+// GetWorkspaces 获取当前用户加入的工作区
+func GetWorkspaces(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var rows []struct {
+		models.Workspace
+		Role string
+	}
+	if err := db.Table("workspaces").
+		Select("workspaces.*, memberships.role").
+		Joins("JOIN memberships ON memberships.workspace_id = workspaces.id").
+		Where("memberships.user_id = ?", userID).
+		Order("workspaces.personal DESC, workspaces.id ASC").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取工作区失败"})
+		return
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	counts, err := loadMemberCounts(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取工作区失败"})
+		return
+	}
+
+	response := make([]models.WorkspaceResponse, len(rows))
+	for i, row := range rows {
+		response[i] = toWorkspaceResponse(row.Workspace, row.Role, counts[row.ID])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateWorkspace 创建工作区，创建者成为所有者
+func CreateWorkspace(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	name, ok := bindWorkspaceName(c)
+	if !ok {
+		return
+	}
+
+	workspace := models.Workspace{Name: name, OwnerID: userID.(uint)}
+	tx := db.Begin()
+	if err := tx.Create(&workspace).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建工作区失败"})
+		return
+	}
+	if err := tx.Create(&models.Membership{WorkspaceID: workspace.ID, UserID: userID.(uint), Role: models.RoleOwner}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建工作区失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建工作区失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toWorkspaceResponse(workspace, models.RoleOwner, 1))
+}
+
+// UpdateWorkspace 重命名工作区，所有者和管理员可以操作
+func UpdateWorkspace(c *gin.Context) {
+	workspace, membership, ok := findWorkspace(c, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	name, ok := bindWorkspaceName(c)
+	if !ok {
+		return
+	}
+	if err := db.Model(&workspace).Update("name", name).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新工作区失败"})
+		return
+	}
+
+	counts, err := loadMemberCounts([]uint{workspace.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新工作区失败"})
+		return
+	}
+	c.JSON(http.StatusOK, toWorkspaceResponse(workspace, membership.Role, counts[workspace.ID]))
+}
+
+// GetMembers 获取工作区成员，按加入时间排列
+func GetMembers(c *gin.Context) {
+	workspace, _, ok := findWorkspace(c, models.RoleGuest)
+	if !ok {
+		return
+	}
+
+	var rows []struct {
+		models.Membership
+		Username string
+	}
+	if err := db.Table("memberships").
+		Select("memberships.*, users.username").
+		Joins("JOIN users ON users.id = memberships.user_id").
+		Where("memberships.workspace_id = ?", workspace.ID).
+		Order("memberships.id ASC").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员失败"})
+		return
+	}
+
+	members := make([]models.MemberResponse, len(rows))
+	for i, row := range rows {
+		members[i] = models.MemberResponse{
+			User:     models.UserSummary{ID: row.UserID, Username: row.Username},
+			Role:     row.Role,
+			JoinedAt: row.CreatedAt,
+		}
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// UpdateMemberRole 修改成员角色，只能管理角色比自己低的成员，授予的角色也必须比自己低
+func UpdateMemberRole(c *gin.Context) {
+	workspace, membership, ok := findWorkspace(c, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	var memberReq MemberRequest
+	if err := c.ShouldBindJSON(&memberReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if _, ok := roleRanks[memberReq.Role]; !ok || memberReq.Role == models.RoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的角色，可选值为: admin, member, guest"})
+		return
+	}
+	target, ok := findMember(c, workspace.ID, memberReq.Username)
+	if !ok {
+		return
+	}
+	if roleRanks[target.Role] >= roleRanks[membership.Role] || roleRanks[memberReq.Role] >= roleRanks[membership.Role] {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能管理角色比自己低的成员"})
+		return
+	}
+
+	if err := db.Model(&target).Update("role", memberReq.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "修改角色失败"})
+		return
+	}
+	GetMembers(c)
+}
+
+// RemoveMember 移除成员，成员也可以移除自己以退出工作区，所有者不能退出
+// 同时移除该成员在工作区任务上的共享和负责人记录以及该成员的Webhook
+// 成员创建的任务和项目保留在工作区中，转给工作区所有者
+func RemoveMember(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	workspace, membership, ok := findWorkspace(c, models.RoleGuest)
+	if !ok {
+		return
+	}
+
+	var memberReq MemberRequest
+	if err := c.ShouldBindJSON(&memberReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	target, ok := findMember(c, workspace.ID, memberReq.Username)
+	if !ok {
+		return
+	}
+	if target.Role == models.RoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能移除工作区所有者"})
+		return
+	}
+	if target.UserID != userID.(uint) &&
+		(roleRanks[membership.Role] < roleRanks[models.RoleAdmin] || roleRanks[target.Role] >= roleRanks[membership.Role]) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能管理角色比自己低的成员"})
+		return
+	}
+
+	workspaceTasks := db.Unscoped().Model(&models.Task{}).Select("id").Where("workspace_id = ?", workspace.ID).SubQuery()
+	tx := db.Begin()
+	if err := tx.Where("user_id = ? AND task_id IN ?", target.UserID, workspaceTasks).Delete(&models.TaskShare{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除成员失败"})
+		return
+	}
+	if err := tx.Where("user_id = ? AND task_id IN ?", target.UserID, workspaceTasks).Delete(&models.TaskAssignee{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除成员失败"})
+		return
+	}
+	if err := deleteUserWebhooks(tx, target.UserID, workspace.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除成员失败"})
+		return
+	}
+	if err := transferMemberTasks(tx, workspace.ID, target.UserID, workspace.OwnerID); err != nil {
+		tx.Rollback()
+		log.Printf("转移成员 %d 的任务失败: %v", target.UserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除成员失败"})
+		return
+	}
+	if err := tx.Delete(&target).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除成员失败"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除成员失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "成员已移除"})
+}
+
+// transferMemberTasks 将成员在工作区中的项目（含自定义工作流）、任务（含回收站中的）和依赖转给新所有者
+// 任务状态按新所有者的工作流校正，避免成员离开后留下无人能访问的任务
+func transferMemberTasks(tx *gorm.DB, workspaceID, fromID, toID uint) error {
+	var projectIDs []uint
+	if err := tx.Unscoped().Model(&models.Project{}).
+		Where("user_id = ? AND workspace_id = ?", fromID, workspaceID).Pluck("id", &projectIDs).Error; err != nil {
+		return err
+	}
+	if len(projectIDs) > 0 {
+		if err := tx.Unscoped().Model(&models.Project{}).Where("id IN (?)", projectIDs).
+			Update("user_id", toID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.WorkflowState{}).Where("user_id = ? AND project_id IN (?)", fromID, projectIDs).
+			Update("user_id", toID).Error; err != nil {
+			return err
+		}
+	}
+
+	var taskIDs []uint
+	if err := tx.Unscoped().Model(&models.Task{}).
+		Where("user_id = ? AND workspace_id = ?", fromID, workspaceID).Pluck("id", &taskIDs).Error; err != nil {
+		return err
+	}
+	// 上传到工作区的文件随任务一起转交，附件的访问权限仍按任务判断
+	if err := tx.Unscoped().Model(&models.File{}).Where("user_id = ? AND workspace_id = ?", fromID, workspaceID).
+		Update("user_id", toID).Error; err != nil {
+		return err
+	}
+	if len(taskIDs) == 0 {
+		return nil
+	}
+	if err := transferTaskTags(tx, taskIDs, fromID, toID); err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Model(&models.Task{}).Where("id IN (?)", taskIDs).
+		Updates(map[string]interface{}{"user_id": toID, "version": bumpVersion}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.TaskDependency{}).Where("user_id = ? AND task_id IN (?)", fromID, taskIDs).
+		Update("user_id", toID).Error; err != nil {
+		return err
+	}
+	// 新所有者不再需要共享记录
+	if err := tx.Where("user_id = ? AND task_id IN (?)", toID, taskIDs).Delete(&models.TaskShare{}).Error; err != nil {
+		return err
+	}
+	return syncTaskStatuses(tx, toID, taskIDs)
+}
+
+// transferTaskTags 将任务关联的标签换成新所有者的同名标签，没有同名标签时按原颜色创建
+// 原所有者的标签仍在其他工作区中使用，保持不变
+func transferTaskTags(tx *gorm.DB, taskIDs []uint, fromID, toID uint) error {
+	var tags []models.Tag
+	if err := tx.Where("user_id = ? AND id IN ?", fromID,
+		tx.Table("task_tags").Select("tag_id").Where("task_id IN (?)", taskIDs).SubQuery()).
+		Find(&tags).Error; err != nil {
+		return err
+	}
+	for _, old := range tags {
+		tag := models.Tag{UserID: toID, Name: old.Name}
+		if err := tx.Where(models.Tag{UserID: toID, Name: old.Name}).
+			Attrs(models.Tag{Color: old.Color}).
+			FirstOrCreate(&tag).Error; err != nil {
+			return err
+		}
+		// 已经带有同名标签的任务只需去掉原标签，避免重复关联
+		var tagged []uint
+		if err := tx.Table("task_tags").Where("tag_id = ? AND task_id IN (?)", tag.ID, taskIDs).
+			Pluck("task_id", &tagged).Error; err != nil {
+			return err
+		}
+		if len(tagged) > 0 {
+			if err := tx.Exec("DELETE FROM task_tags WHERE tag_id = ? AND task_id IN (?)", old.ID, tagged).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("UPDATE task_tags SET tag_id = ? WHERE tag_id = ? AND task_id IN (?)", tag.ID, old.ID, taskIDs).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InviteMember 邀请用户加入工作区，所有者和管理员可以操作
+// 返回的令牌只出现一次，可以通过邀请链接发给对方
+func InviteMember(c *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	workspace, membership, ok := findWorkspace(c, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	var inviteReq InviteRequest
+	if err := c.ShouldBindJSON(&inviteReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if inviteReq.Role == "" {
+		inviteReq.Role = models.RoleMember
+	}
+	if _, ok := roleRanks[inviteReq.Role]; !ok || inviteReq.Role == models.RoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的角色，可选值为: admin, member, guest"})
+		return
+	}
+	if roleRanks[inviteReq.Role] >= roleRanks[membership.Role] {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能邀请角色比自己低的成员"})
+		return
+	}
+
+	invitation := models.Invitation{
+		WorkspaceID: workspace.ID,
+		InviterID:   userID.(uint),
+		Role:        inviteReq.Role,
+		Status:      models.InvitationPending,
+		ExpiresAt:   time.Now().Add(invitationTTL),
+	}
+
+	// 按用户名邀请时用户必须存在，按邮箱邀请时对应已注册用户则直接关联
+	var invitee models.User
+	username := strings.TrimSpace(inviteReq.Username)
+	email := strings.TrimSpace(inviteReq.Email)
+	switch {
+	case username != "":
+		if db.Where("username = ?", username).First(&invitee).RecordNotFound() {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+			return
+		}
+	case email != "":
+		invitation.Email = email
+		db.Where("email = ?", email).First(&invitee)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供被邀请的用户名或邮箱"})
+		return
+	}
+	if invitee.ID != 0 {
+		if isWorkspaceMember(workspace.ID, invitee.ID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "该用户已是工作区成员"})
+			return
+		}
+		invitation.InviteeID = &invitee.ID
+	}
+
+	// 与刷新令牌相同，只保存令牌摘要
+	raw, err := generateRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建邀请失败"})
+		return
+	}
+	invitation.TokenHash = hashToken(raw)
+	if err := db.Create(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建邀请失败"})
+		return
+	}
+
+	responses, err := buildInvitationResponses([]models.Invitation{invitation})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建邀请失败"})
+		return
+	}
+	responses[0].Token = raw
+	c.JSON(http.StatusOK, responses[0])
+}
+
+// GetWorkspaceInvitations 获取工作区待处理的邀请，所有者和管理员可以查看
+func GetWorkspaceInvitations(c *gin.Context) {
+	workspace, _, ok := findWorkspace(c, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	var invitations []models.Invitation
+	if err := db.Where("workspace_id = ? AND status = ? AND expires_at > ?", workspace.ID, models.InvitationPending, time.Now()).
+		Order("id DESC").Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取邀请失败"})
+		return
+	}
+	respondInvitations(c, invitations)
+}
+
+// GetMyInvitations 获取发给当前用户的待处理邀请，按用户或邮箱匹配
+func GetMyInvitations(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	query := db.Where("status = ? AND expires_at > ?", models.InvitationPending, time.Now())
+	if user.Email != "" {
+		query = query.Where("invitee_id = ? OR (invitee_id IS NULL AND email = ?)", user.ID, user.Email)
+	} else {
+		query = query.Where("invitee_id = ?", user.ID)
+	}
+	var invitations []models.Invitation
+	if err := query.Order("id DESC").Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取邀请失败"})
+		return
+	}
+	respondInvitations(c, invitations)
+}
+
+// AcceptInvitation 接受邀请，加入工作区
+func AcceptInvitation(c *gin.Context) {
+	user, invitation, ok := findInvitation(c)
+	if !ok {
+		return
+	}
+
+	// 只有仍待处理的邀请才能接受，同一邀请被同时接受或拒绝时只有一个请求成功
+	now := time.Now()
+	tx := db.Begin()
+	result := tx.Model(&models.Invitation{}).
+		Where("id = ? AND status = ?", invitation.ID, models.InvitationPending).
+		Updates(map[string]interface{}{
+			"status":       models.InvitationAccepted,
+			"invitee_id":   user.ID,
+			"responded_at": now,
+		})
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "接受邀请失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "邀请已处理"})
+		return
+	}
+	var count int
+	if err := tx.Model(&models.Membership{}).
+		Where("workspace_id = ? AND user_id = ?", invitation.WorkspaceID, user.ID).Count(&count).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "接受邀请失败"})
+		return
+	}
+	if count == 0 {
+		if err := tx.Create(&models.Membership{WorkspaceID: invitation.WorkspaceID, UserID: user.ID, Role: invitation.Role}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "接受邀请失败"})
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "接受邀请失败"})
+		return
+	}
+
+	var workspace models.Workspace
+	db.First(&workspace, invitation.WorkspaceID)
+	counts, err := loadMemberCounts([]uint{workspace.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "接受邀请失败"})
+		return
+	}
+	var membership models.Membership
+	db.Where("workspace_id = ? AND user_id = ?", workspace.ID, user.ID).First(&membership)
+	c.JSON(http.StatusOK, toWorkspaceResponse(workspace, membership.Role, counts[workspace.ID]))
+}
+
+// DeclineInvitation 拒绝邀请
+func DeclineInvitation(c *gin.Context) {
+	user, invitation, ok := findInvitation(c)
+	if !ok {
+		return
+	}
+
+	result := db.Model(&models.Invitation{}).
+		Where("id = ? AND status = ?", invitation.ID, models.InvitationPending).
+		Updates(map[string]interface{}{
+			"status":       models.InvitationDeclined,
+			"invitee_id":   user.ID,
+			"responded_at": time.Now(),
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "拒绝邀请失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "邀请已处理"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已拒绝邀请"})
+}
+
+// findWorkspace 根据URL参数查找当前用户所在的工作区，并要求角色不低于minRole
+// 不是成员时返回404，角色不足时返回403；失败时已写入错误响应
+func findWorkspace(c *gin.Context, minRole string) (models.Workspace, models.Membership, bool) {
+	var workspace models.Workspace
+	var membership models.Membership
+
+	// 从上下文中获取用户ID
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return workspace, membership, false
+	}
+
+	// 获取工作区ID
+	workspaceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的工作区ID"})
+		return workspace, membership, false
+	}
+
+	if db.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&membership).RecordNotFound() ||
+		db.First(&workspace, workspaceID).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "工作区不存在或无权限"})
+		return workspace, membership, false
+	}
+	if roleRanks[membership.Role] < roleRanks[minRole] {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有管理该工作区的权限"})
+		return workspace, membership, false
+	}
+	return workspace, membership, true
+}
+
+// findMember 按用户名查找工作区成员，失败时已写入错误响应
+func findMember(c *gin.Context, workspaceID uint, username string) (models.Membership, bool) {
+	var membership models.Membership
+	user, ok := findUserByName(c, username)
+	if !ok {
+		return membership, false
+	}
+	if db.Where("workspace_id = ? AND user_id = ?", workspaceID, user.ID).First(&membership).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "该用户不是工作区成员"})
+		return membership, false
+	}
+	return membership, true
+}
+
+// currentUser 加载当前登录的用户，失败时已写入错误响应
+func currentUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return user, false
+	}
+	if db.First(&user, userID).RecordNotFound() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return user, false
+	}
+	return user, true
+}
+
+// findInvitation 根据令牌或ID查找发给当前用户的待处理邀请，失败时已写入错误响应
+// 持有令牌即可响应按邮箱发出的邀请；按ID响应时邮箱必须与当前用户一致
+func findInvitation(c *gin.Context) (models.User, models.Invitation, bool) {
+	var invitation models.Invitation
+	user, ok := currentUser(c)
+	if !ok {
+		return user, invitation, false
+	}
+
+	var replyReq InvitationReplyRequest
+	if err := c.ShouldBindJSON(&replyReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return user, invitation, false
+	}
+	var notFound bool
+	switch {
+	case replyReq.Token != "":
+		notFound = db.Where("token_hash = ?", hashToken(replyReq.Token)).First(&invitation).RecordNotFound()
+	case replyReq.ID != 0:
+		notFound = db.First(&invitation, replyReq.ID).RecordNotFound() ||
+			(invitation.InviteeID == nil && (user.Email == "" || invitation.Email != user.Email))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供邀请令牌或邀请ID"})
+		return user, invitation, false
+	}
+	if notFound || (invitation.InviteeID != nil && *invitation.InviteeID != user.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "邀请不存在或无权限"})
+		return user, invitation, false
+	}
+	if invitation.Status != models.InvitationPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "邀请已处理"})
+		return user, invitation, false
+	}
+	if time.Now().After(invitation.ExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "邀请已过期"})
+		return user, invitation, false
+	}
+	return user, invitation, true
+}
+
+// bindWorkspaceName 绑定并校验工作区名称，失败时已写入错误响应
+func bindWorkspaceName(c *gin.Context) (string, bool) {
+	var workspaceReq WorkspaceRequest
+	if err := c.ShouldBindJSON(&workspaceReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的工作区数据"})
+		return "", false
+	}
+	name := strings.TrimSpace(workspaceReq.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "工作区名称不能为空"})
+		return "", false
+	}
+	return name, true
+}
+
+// loadMemberCounts 统计各工作区的成员数
+func loadMemberCounts(workspaceIDs []uint) (map[uint]int, error) {
+	counts := make(map[uint]int, len(workspaceIDs))
+	if len(workspaceIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		WorkspaceID uint
+		Count       int
+	}
+	if err := db.Model(&models.Membership{}).
+		Select("workspace_id, COUNT(*) AS count").
+		Where("workspace_id IN (?)", workspaceIDs).
+		Group("workspace_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.WorkspaceID] = row.Count
+	}
+	return counts, nil
+}
+
+// respondInvitations 返回邀请列表
+func respondInvitations(c *gin.Context, invitations []models.Invitation) {
+	responses, err := buildInvitationResponses(invitations)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取邀请失败"})
+		return
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// buildInvitationResponses 加载邀请关联的工作区和用户，生成响应
+func buildInvitationResponses(invitations []models.Invitation) ([]models.InvitationResponse, error) {
+	responses := make([]models.InvitationResponse, len(invitations))
+	if len(invitations) == 0 {
+		return responses, nil
+	}
+
+	var workspaceIDs, userIDs []uint
+	for _, invitation := range invitations {
+		workspaceIDs = append(workspaceIDs, invitation.WorkspaceID)
+		userIDs = append(userIDs, invitation.InviterID)
+		if invitation.InviteeID != nil {
+			userIDs = append(userIDs, *invitation.InviteeID)
+		}
+	}
+	var workspaces []models.Workspace
+	if err := db.Where("id IN (?)", workspaceIDs).Find(&workspaces).Error; err != nil {
+		return nil, err
+	}
+	var users []models.User
+	if err := db.Select("id, username").Where("id IN (?)", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	workspaceRefs := make(map[uint]models.WorkspaceRef, len(workspaces))
+	for _, workspace := range workspaces {
+		workspaceRefs[workspace.ID] = models.WorkspaceRef{ID: workspace.ID, Name: workspace.Name}
+	}
+	usernames := make(map[uint]string, len(users))
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+	}
+
+	for i, invitation := range invitations {
+		responses[i] = models.InvitationResponse{
+			ID:        invitation.ID,
+			Workspace: workspaceRefs[invitation.WorkspaceID],
+			Inviter:   models.UserSummary{ID: invitation.InviterID, Username: usernames[invitation.InviterID]},
+			Email:     invitation.Email,
+			Role:      invitation.Role,
+			Status:    invitation.Status,
+			ExpiresAt: invitation.ExpiresAt,
+			CreatedAt: invitation.CreatedAt,
+		}
+		if invitation.InviteeID != nil {
+			responses[i].Invitee = &models.UserSummary{ID: *invitation.InviteeID, Username: usernames[*invitation.InviteeID]}
+		}
+	}
+	return responses, nil
+}
+
+// toWorkspaceResponse 将工作区模型转换为响应模型
+func toWorkspaceResponse(workspace models.Workspace, role string, memberCount int) models.WorkspaceResponse {
+	return models.WorkspaceResponse{
+		ID:          workspace.ID,
+		Name:        workspace.Name,
+		OwnerID:     workspace.OwnerID,
+		Personal:    workspace.Personal,
+		Role:        role,
+		MemberCount: memberCount,
+		CreatedAt:   workspace.CreatedAt,
+	}
+}
@@ -0,0 +1,81 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 每行最多的字节数（不含换行），超过时折行
+const maxLineOctets = 75
+
+// 日期时间的UTC格式
+const utcLayout = "20060102T150405Z"
+
+// textEscaper 转义TEXT类型的值
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// Calendar 按RFC 5545逐行生成iCalendar内容
+// 行以CRLF结尾，超过75字节的行在字符边界处折行
+type Calendar struct {
+	buf bytes.Buffer
+}
+
+// Begin 开始一个组件，如VCALENDAR、VEVENT
+func (c *Calendar) Begin(component string) {
+	c.line("BEGIN:" + component)
+}
+
+// End 结束一个组件
+func (c *Calendar) End(component string) {
+	c.line("END:" + component)
+}
+
+// Prop 写入一个属性，value原样写入，调用方负责格式正确
+func (c *Calendar) Prop(name, value string) {
+	c.line(name + ":" + value)
+}
+
+// Text 写入TEXT类型的属性，转义其中的特殊字符
+func (c *Calendar) Text(name, value string) {
+	c.Prop(name, Escape(value))
+}
+
+// Time 写入UTC时间的属性，如DTSTART、DUE
+func (c *Calendar) Time(name string, t time.Time) {
+	c.Prop(name, t.UTC().Format(utcLayout))
+}
+
+// Bytes 返回已生成的内容
+func (c *Calendar) Bytes() []byte {
+	return c.buf.Bytes()
+}
+
+// line 写入一行，超长时折行，续行以一个空格开头
+func (c *Calendar) line(s string) {
+	width := 0
+	for len(s) > 0 {
+		_, size := utf8.DecodeRuneInString(s)
+		if width+size > maxLineOctets {
+			c.buf.WriteString("\r\n ")
+			width = 1
+		}
+		c.buf.WriteString(s[:size])
+		width += size
+		s = s[size:]
+	}
+	c.buf.WriteString("\r\n")
+}
+
+// Escape 转义TEXT类型的值中的反斜杠、分号、逗号和换行
+func Escape(s string) string {
+	return textEscaper.Replace(s)
+}
@@ -0,0 +1,118 @@
+package ical
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// 本地日期时间的格式，配合TZID参数使用
+const localLayout = "20060102T150405"
+
+// TZID 返回时区的标识，尽量使用IANA名称（如 Asia/Shanghai），以便日历客户端识别
+// time.Local没有名称时依次从TZ环境变量和/etc/localtime链接中查找，都找不到时为Local，
+// 此时客户端只能依靠VTIMEZONE中的定义
+func TZID(loc *time.Location) string {
+	name := loc.String()
+	if name != "Local" {
+		return name
+	}
+	if tz := strings.TrimPrefix(os.Getenv("TZ"), ":"); tz != "" && !strings.HasPrefix(tz, "/") {
+		return tz
+	}
+	if target, err := os.Readlink("/etc/localtime"); err == nil {
+		if i := strings.Index(target, "zoneinfo/"); i >= 0 {
+			return target[i+len("zoneinfo/"):]
+		}
+	}
+	return name
+}
+
+// isUTC 判断时区是否为UTC，UTC时间直接使用Z后缀，不需要VTIMEZONE
+func isUTC(loc *time.Location) bool {
+	return loc == time.UTC || loc.String() == "UTC"
+}
+
+// LocalTime 写入带TZID的本地时间属性，如 DTSTART;TZID=Asia/Shanghai:20231001T090000
+// 重复规则按该时区展开，BYDAY等规则与本地日期一致；loc为UTC时与Time相同
+// 使用非UTC时区时，日历中需要有对应的Timezone
+func (c *Calendar) LocalTime(name string, t time.Time, loc *time.Location) {
+	if isUTC(loc) {
+		c.Time(name, t)
+		return
+	}
+	c.Prop(name+";TZID="+TZID(loc), t.In(loc).Format(localLayout))
+}
+
+// Timezone 写入时区的VTIMEZONE定义，loc为UTC时不写入
+// 按year年内的时区变化生成：没有夏令时的时区只有一个STANDARD，有夏令时的时区
+// 以"某月第几个（或最后一个）星期几"的规则每年重复，适用于目前绝大多数实行夏令时的地区
+func (c *Calendar) Timezone(loc *time.Location, year int) {
+	if isUTC(loc) {
+		return
+	}
+	c.Begin("VTIMEZONE")
+	c.Prop("TZID", TZID(loc))
+
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(1, 0, 0)
+	var transitions []time.Time
+	for t := start; ; {
+		_, next := t.ZoneBounds()
+		if next.IsZero() || !next.Before(end) {
+			break
+		}
+		transitions = append(transitions, next)
+		t = next
+	}
+
+	if len(transitions) == 0 {
+		abbr, offset := start.Zone()
+		c.Begin("STANDARD")
+		c.Prop("DTSTART", "19700101T000000")
+		c.Prop("TZOFFSETFROM", formatOffset(offset))
+		c.Prop("TZOFFSETTO", formatOffset(offset))
+		c.Text("TZNAME", abbr)
+		c.End("STANDARD")
+	}
+	for _, t := range transitions {
+		_, from := t.Add(-time.Second).Zone()
+		abbr, to := t.Zone()
+		component := "STANDARD"
+		if t.IsDST() {
+			component = "DAYLIGHT"
+		}
+		// 变化时刻按变化前的偏移表示
+		onset := t.In(time.FixedZone("", from))
+		c.Begin(component)
+		c.Prop("DTSTART", onset.Format(localLayout))
+		c.Prop("TZOFFSETFROM", formatOffset(from))
+		c.Prop("TZOFFSETTO", formatOffset(to))
+		c.Text("TZNAME", abbr)
+		c.Prop("RRULE", fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d;BYDAY=%d%s", onset.Month(), weekOfMonth(onset), weekdayCodes[onset.Weekday()]))
+		c.End(component)
+	}
+	c.End("VTIMEZONE")
+}
+
+// weekdayCodes 星期的iCalendar缩写
+var weekdayCodes = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// weekOfMonth 返回t是当月第几个同样的星期几，是最后一个时返回-1
+func weekOfMonth(t time.Time) int {
+	if t.AddDate(0, 0, 7).Month() != t.Month() {
+		return -1
+	}
+	return (t.Day()-1)/7 + 1
+}
+
+// formatOffset 将相对UTC的秒数格式化为 +0800 的形式
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
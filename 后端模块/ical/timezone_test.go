@@ -0,0 +1,77 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("没有时区数据 %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestLocalTime(t *testing.T) {
+	due := time.Date(2026, 10, 20, 10, 0, 0, 0, time.UTC)
+
+	var cal Calendar
+	cal.LocalTime("DTSTART", due, loadLocation(t, "Asia/Shanghai"))
+	cal.LocalTime("DUE", due, time.UTC)
+	want := "DTSTART;TZID=Asia/Shanghai:20261020T180000\r\nDUE:20261020T100000Z\r\n"
+	if got := string(cal.Bytes()); got != want {
+		t.Errorf("LocalTime =\n%q\n期望\n%q", got, want)
+	}
+}
+
+func TestTimezone(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Asia/Shanghai", []string{
+			"BEGIN:STANDARD", "DTSTART:19700101T000000", "TZOFFSETFROM:+0800", "TZOFFSETTO:+0800", "END:STANDARD",
+		}},
+		{"America/New_York", []string{
+			"BEGIN:DAYLIGHT", "DTSTART:20260308T020000", "TZOFFSETFROM:-0500", "TZOFFSETTO:-0400", "TZNAME:EDT",
+			"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU", "END:DAYLIGHT",
+			"BEGIN:STANDARD", "DTSTART:20261101T020000", "TZOFFSETFROM:-0400", "TZOFFSETTO:-0500", "TZNAME:EST",
+			"RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU", "END:STANDARD",
+		}},
+		{"Europe/Berlin", []string{
+			"BEGIN:DAYLIGHT", "DTSTART:20260329T020000", "RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU", "END:DAYLIGHT",
+			"BEGIN:STANDARD", "DTSTART:20261025T030000", "RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU", "END:STANDARD",
+		}},
+	}
+	for _, tt := range tests {
+		var cal Calendar
+		cal.Timezone(loadLocation(t, tt.name), 2026)
+		got := string(cal.Bytes())
+		if !strings.HasPrefix(got, "BEGIN:VTIMEZONE\r\nTZID:"+tt.name+"\r\n") || !strings.HasSuffix(got, "END:VTIMEZONE\r\n") {
+			t.Errorf("%s: VTIMEZONE格式错误:\n%s", tt.name, got)
+		}
+		if !containsInOrder(got, tt.want) {
+			t.Errorf("%s: VTIMEZONE中缺少 %v:\n%s", tt.name, tt.want, got)
+		}
+	}
+
+	var cal Calendar
+	cal.Timezone(time.UTC, 2026)
+	if len(cal.Bytes()) != 0 {
+		t.Errorf("UTC不应写入VTIMEZONE: %s", cal.Bytes())
+	}
+}
+
+// containsInOrder 判断lines是否按顺序出现在s的各行中
+func containsInOrder(s string, lines []string) bool {
+	i := 0
+	for _, line := range strings.Split(s, "\r\n") {
+		if i < len(lines) && line == lines[i] {
+			i++
+		}
+	}
+	return i == len(lines)
+}
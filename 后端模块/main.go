@@ -15,6 +15,7 @@ import (
 	"taskmanager/controllers"
 	"taskmanager/middleware"
 	"taskmanager/models"
+	"taskmanager/token"
 )
 
 // 全局数据库连接
@@ -31,9 +32,15 @@ func main() {
 	initDB()
 	defer db.Close()
 
+	// 初始化JWT密钥
+	token.Init(appConfig.JWT)
+
 	// 初始化MinIO客户端
 	config.InitMinio()
 
+	// 为升级前上传的文件补齐元数据
+	controllers.InitFiles()
+
 	// 初始化路由
 	router := initRouter()
 
@@ -79,10 +86,32 @@ func initDB() {
 	db.LogMode(true)
 
 	// 自动迁移模式
-	db.AutoMigrate(&models.User{}, &models.Task{})
+	db.AutoMigrate(&models.User{}, &models.Task{}, &models.Project{}, &models.Tag{}, &models.File{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.TaskEvent{}, &models.Comment{}, &models.CommentMention{}, &models.WorkflowState{}, &models.TaskDependency{}, &models.TaskShare{}, &models.TaskAssignee{}, &models.Workspace{}, &models.Membership{}, &models.Invitation{}, &models.TaskReminder{}, &models.ReminderDelivery{}, &models.Notification{}, &models.Webhook{}, &models.WebhookDelivery{}, &models.CalendarFeed{})
 
-	// 将数据库连接传递给控制器
+	// 将数据库连接传递给控制器和中间件
 	controllers.SetDB(db)
+	middleware.SetDB(db)
+
+	// 为升级前注册的用户创建个人工作区
+	controllers.InitWorkspaces()
+
+	// 创建任务搜索使用的全文索引
+	controllers.InitTaskSearch()
+
+	// 为升级前的任务补齐工作流状态
+	controllers.InitWorkflow()
+
+	// 启动回收站定时清理
+	controllers.InitTrash(appConfig.Trash)
+
+	// 启动到期提醒的后台发送
+	controllers.InitReminders(appConfig.Reminder)
+
+	// 创建实时推送的事件中心
+	controllers.InitStream(appConfig.Stream)
+
+	// 启动Webhook的后台发送
+	controllers.InitWebhooks(appConfig.Webhook)
 
 	log.Println("数据库连接成功")
 }
@@ -100,27 +129,122 @@ func initRouter() *gin.Engine {
 		// 用户相关路由
 		api.POST("/register", controllers.Register)
 		api.POST("/login", controllers.Login)
+		api.POST("/token/refresh", controllers.RefreshToken)
+		api.GET("/calendar/:token", controllers.GetCalendarFeed) // 日历订阅，通过地址中的令牌认证
 
 		// 需要认证的路由
 		auth := api.Group("/")
 		auth.Use(middleware.JWTAuth())
 		{
 			auth.GET("/user/info", controllers.GetUserInfo)
+			auth.POST("/logout", controllers.Logout)
+			auth.GET("/events", controllers.StreamEvents) // 以Server-Sent Events推送任务和文件变更
 
 			// 任务相关路由
 			// 按照规范，只使用GET和POST请求
 			auth.GET("/tasks", controllers.GetTasks)
+			auth.GET("/tasks/search", controllers.SearchTasks)      // 按关键词搜索任务
+			auth.GET("/tasks/trash", controllers.GetTrash)          // 回收站中的任务
+			auth.GET("/tasks/plan", controllers.GetTaskPlan)        // 按依赖关系排序的执行计划
+			auth.POST("/tasks/trash/empty", controllers.EmptyTrash) // 清空回收站
+			auth.POST("/task/restore/:id", controllers.RestoreTask) // 从回收站恢复任务
+			auth.POST("/task/purge/:id", controllers.PurgeTask)     // 永久删除回收站中的任务
 			auth.POST("/task", controllers.CreateTask)
-			auth.POST("/task/update/:id", controllers.UpdateTask) // 使用POST替代PUT
-			auth.POST("/task/delete/:id", controllers.DeleteTask) // 使用POST替代DELETE
+			auth.POST("/task/update/:id", controllers.UpdateTask)     // 使用POST替代PUT
+			auth.POST("/task/delete/:id", controllers.DeleteTask)     // 使用POST替代DELETE
+			auth.POST("/task/subtask/:id", controllers.CreateSubtask) // 在指定任务下创建子任务
+			auth.POST("/task/move/:id", controllers.MoveTask)         // 移动任务到其他父任务下
+			auth.GET("/task/:id/history", controllers.GetTaskHistory) // 任务变更历史
+
+			// 评论相关路由
+			auth.GET("/task/:id/comments", controllers.GetComments)
+			auth.POST("/task/comment/:id", controllers.CreateComment) // 在指定任务下发表评论
+			auth.POST("/comment/update/:id", controllers.UpdateComment)
+			auth.POST("/comment/delete/:id", controllers.DeleteComment)
+
+			// 附件相关路由
+			auth.GET("/task/:id/attachments", controllers.GetTaskAttachments)
+			auth.POST("/task/:id/attachments", controllers.UploadTaskAttachment)        // 上传文件到任务
+			auth.POST("/task/:id/attachments/link", controllers.LinkTaskAttachment)     // 添加已上传的文件
+			auth.POST("/task/:id/attachments/unlink", controllers.UnlinkTaskAttachment) // 从任务中移除附件
+
+			// 任务依赖相关路由
+			auth.GET("/task/:id/dependencies", controllers.GetTaskDependencies)
+			auth.POST("/task/:id/dependencies/add", controllers.AddTaskDependency)       // 添加阻塞任务
+			auth.POST("/task/:id/dependencies/remove", controllers.RemoveTaskDependency) // 移除阻塞任务
+
+			// 任务共享与负责人相关路由
+			auth.GET("/task/:id/shares", controllers.GetTaskShares)
+			auth.POST("/task/:id/shares/add", controllers.ShareTask)                // 共享任务或修改共享权限
+			auth.POST("/task/:id/shares/remove", controllers.UnshareTask)           // 取消共享
+			auth.POST("/task/:id/assignees/add", controllers.AddTaskAssignee)       // 添加负责人
+			auth.POST("/task/:id/assignees/remove", controllers.RemoveTaskAssignee) // 移除负责人
+
+			// 任务提醒相关路由
+			auth.GET("/task/:id/reminders", controllers.GetTaskReminders)
+			auth.POST("/task/:id/reminders", controllers.SetTaskReminders) // 整体替换任务的提醒
+
+			// 通知相关路由
+			auth.GET("/notifications", controllers.GetNotifications)
+			auth.GET("/notifications/unread-count", controllers.GetUnreadNotificationCount) // 未读通知数
+			auth.POST("/notifications/read-all", controllers.MarkAllNotificationsRead)      // 全部标记为已读
+			auth.POST("/notification/read/:id", controllers.MarkNotificationRead)
+			auth.POST("/notification/unread/:id", controllers.MarkNotificationUnread)
+
+			// Webhook相关路由
+			auth.GET("/webhooks", controllers.GetWebhooks)
+			auth.POST("/webhook", controllers.CreateWebhook)
+			auth.POST("/webhook/update/:id", controllers.UpdateWebhook)
+			auth.POST("/webhook/delete/:id", controllers.DeleteWebhook)
+			auth.POST("/webhook/test/:id", controllers.TestWebhook)               // 立即发送测试事件
+			auth.GET("/webhook/:id/deliveries", controllers.GetWebhookDeliveries) // 投递日志
+
+			// 日历订阅相关路由
+			auth.GET("/user/calendar", controllers.GetCalendarFeedInfo)
+			auth.POST("/user/calendar/regenerate", controllers.RegenerateCalendarFeed) // 生成新的订阅地址，旧地址失效
+			auth.POST("/user/calendar/disable", controllers.DisableCalendarFeed)
+
+			// 项目相关路由
+			auth.GET("/projects", controllers.GetProjects)
+			auth.POST("/project", controllers.CreateProject)
+			auth.POST("/project/update/:id", controllers.UpdateProject)
+			auth.POST("/project/delete/:id", controllers.DeleteProject)
+			auth.GET("/project/:id/tasks", controllers.GetProjectTasks)
+			auth.POST("/task/project/:id", controllers.MoveTaskToProject) // 移动任务到其他项目
+
+			// 工作流与看板相关路由
+			auth.GET("/workflow", controllers.GetWorkflow)
+			auth.POST("/workflow", controllers.UpdateWorkflow)        // 整体替换工作流状态
+			auth.GET("/board", controllers.GetBoard)                  // 按状态分列的看板
+			auth.POST("/task/status/:id", controllers.MoveTaskStatus) // 在看板中移动任务
+
+			// 标签相关路由
+			auth.GET("/tags", controllers.GetTags)
+			auth.POST("/tag", controllers.CreateTag)
+			auth.POST("/tag/update/:id", controllers.UpdateTag)
+			auth.POST("/tag/delete/:id", controllers.DeleteTag)
 
 			// 文件相关路由
-			auth.POST("/file/upload", controllers.UploadFile)           // 上传文件
-			auth.GET("/files", controllers.GetFileList)                 // 获取文件列表
-			auth.POST("/file/delete/:fileName", controllers.DeleteFile) // 删除文件
+			auth.POST("/file/upload", controllers.UploadFile)        // 上传文件
+			auth.GET("/files", controllers.GetFileList)              // 获取文件列表
+			auth.GET("/file/:id/download", controllers.DownloadFile) // 下载文件（重定向到预签名链接）
+			auth.POST("/file/delete/:id", controllers.DeleteFile)    // 删除文件
 
 			// 头像相关路由
 			auth.POST("/user/avatar", controllers.UploadAvatar) // 上传用户头像
+
+			// 工作区相关路由
+			auth.GET("/workspaces", controllers.GetWorkspaces)
+			auth.POST("/workspace", controllers.CreateWorkspace)
+			auth.POST("/workspace/update/:id", controllers.UpdateWorkspace)
+			auth.GET("/workspace/:id/members", controllers.GetMembers)
+			auth.POST("/workspace/:id/members/role", controllers.UpdateMemberRole)      // 修改成员角色
+			auth.POST("/workspace/:id/members/remove", controllers.RemoveMember)        // 移除成员或退出工作区
+			auth.POST("/workspace/:id/invite", controllers.InviteMember)                // 按用户名或邮箱邀请
+			auth.GET("/workspace/:id/invitations", controllers.GetWorkspaceInvitations) // 待处理的邀请
+			auth.GET("/invitations", controllers.GetMyInvitations)                      // 发给我的邀请
+			auth.POST("/invitation/accept", controllers.AcceptInvitation)
+			auth.POST("/invitation/decline", controllers.DeclineInvitation)
 		}
 	}
 
@@ -0,0 +1,191 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Render 将Markdown渲染为HTML，只支持评论中常用的子集：
+//
+//   - 段落与换行，空行分隔段落
+//   - # 标题（渲染为h1-h6）
+//   - - / * 无序列表，1. 有序列表，> 引用
+//   - ``` 代码块，`行内代码`
+//   - **粗体**、*斜体*、~~删除线~~
+//   - [文字](链接)，只允许http、https、mailto链接
+//   - @用户名，仅mentions中的用户名会被标记为提及
+//
+// 原文中的HTML一律转义，输出可以直接插入页面
+func Render(src string, mentions []string) string {
+	known := make(map[string]bool, len(mentions))
+	for _, name := range mentions {
+		known[name] = true
+	}
+
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var out strings.Builder
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case strings.HasPrefix(trimmed, "```"):
+			// 代码块，直到下一个```或文末
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			i++
+			out.WriteString("<pre><code>")
+			out.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			out.WriteString("</code></pre>")
+
+		case headingPattern.MatchString(trimmed):
+			m := headingPattern.FindStringSubmatch(trimmed)
+			tag := "h" + string(rune('0'+len(m[1])))
+			out.WriteString("<" + tag + ">" + renderInline(m[2], known) + "</" + tag + ">")
+			i++
+
+		case strings.HasPrefix(trimmed, ">"):
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				text := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quote = append(quote, strings.TrimPrefix(text, " "))
+			}
+			out.WriteString("<blockquote>" + Render(strings.Join(quote, "\n"), mentions) + "</blockquote>")
+
+		case bulletPattern.MatchString(line), orderedPattern.MatchString(line):
+			pattern, tag := bulletPattern, "ul"
+			if !bulletPattern.MatchString(line) {
+				pattern, tag = orderedPattern, "ol"
+			}
+			out.WriteString("<" + tag + ">")
+			for ; i < len(lines) && pattern.MatchString(lines[i]); i++ {
+				item := pattern.FindStringSubmatch(lines[i])[1]
+				out.WriteString("<li>" + renderInline(item, known) + "</li>")
+			}
+			out.WriteString("</" + tag + ">")
+
+		default:
+			// 段落，遇到空行或其他块级元素时结束
+			var para []string
+			for ; i < len(lines) && !startsBlock(lines[i]); i++ {
+				para = append(para, renderInline(strings.TrimSpace(lines[i]), known))
+			}
+			out.WriteString("<p>" + strings.Join(para, "<br>") + "</p>")
+		}
+	}
+	return out.String()
+}
+
+// Mentions 返回文本中提及的用户名，按首次出现的顺序去重
+// 代码块、行内代码和链接地址中的@不算提及
+func Mentions(src string) []string {
+	src = fencePattern.ReplaceAllString(src, "")
+	src = codeSpanPattern.ReplaceAllString(src, "")
+	src = linkPattern.ReplaceAllString(src, "$1")
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(src, -1) {
+		if name := m[2]; !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+var (
+	headingPattern  = regexp.MustCompile(`^(#{1,6})\s+(.+)$`)
+	bulletPattern   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedPattern  = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	fencePattern    = regexp.MustCompile("(?s)```.*?(```|$)")
+	codeSpanPattern = regexp.MustCompile("`[^`\n]+`")
+	mentionPattern  = regexp.MustCompile(`(^|[^\p{L}\p{N}_@])@([\p{L}\p{N}_]+(?:[.\-][\p{L}\p{N}_]+)*)`)
+	linkPattern     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	boldPattern     = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	italicPattern   = regexp.MustCompile(`\*([^*\s](?:[^*]*[^*\s])?)\*`)
+	strikePattern   = regexp.MustCompile(`~~(.+?)~~`)
+
+	// 行内代码和链接的占位符，原文中的该字符会被去掉
+	placeholderPattern = regexp.MustCompile("\x00\\d+\x00")
+)
+
+// placeholderMark 占位符两端的字符
+const placeholderMark = "\x00"
+
+// startsBlock 判断该行是否结束当前段落
+func startsBlock(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" ||
+		strings.HasPrefix(trimmed, "```") ||
+		strings.HasPrefix(trimmed, ">") ||
+		headingPattern.MatchString(trimmed) ||
+		bulletPattern.MatchString(line) ||
+		orderedPattern.MatchString(line)
+}
+
+// renderInline 渲染行内格式
+// 行内代码和链接先替换为占位符，提及和强调只作用于其余文本，最后再放回，
+// 因此行内代码原样输出，链接地址中的*、@等字符不会被改写
+func renderInline(text string, mentions map[string]bool) string {
+	text = strings.ReplaceAll(text, placeholderMark, "")
+	var tokens []string
+	hold := func(html string) string {
+		tokens = append(tokens, html)
+		return placeholderMark + strconv.Itoa(len(tokens)-1) + placeholderMark
+	}
+
+	text = codeSpanPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return hold("<code>" + html.EscapeString(match[1:len(match)-1]) + "</code>")
+	})
+	text = linkPattern.ReplaceAllStringFunc(text, func(match string) string {
+		m := linkPattern.FindStringSubmatch(match)
+		if !safeURL(m[2]) {
+			return match
+		}
+		return hold(`<a href="` + html.EscapeString(m[2]) + `" target="_blank" rel="noopener noreferrer">` + renderText(m[1], mentions) + `</a>`)
+	})
+	text = renderText(text, mentions)
+
+	// 链接文字中可能还有行内代码的占位符，逐层放回
+	for strings.Contains(text, placeholderMark) {
+		text = placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+			i, _ := strconv.Atoi(strings.Trim(match, placeholderMark))
+			return tokens[i]
+		})
+	}
+	return text
+}
+
+// renderText 转义文本后渲染提及和强调
+func renderText(text string, mentions map[string]bool) string {
+	text = html.EscapeString(text)
+
+	text = mentionPattern.ReplaceAllStringFunc(text, func(match string) string {
+		m := mentionPattern.FindStringSubmatch(match)
+		if !mentions[html.UnescapeString(m[2])] {
+			return match
+		}
+		return m[1] + `<span class="mention">@` + m[2] + `</span>`
+	})
+
+	text = boldPattern.ReplaceAllString(text, "<strong>$1</strong>")
+	text = italicPattern.ReplaceAllString(text, "<em>$1</em>")
+	text = strikePattern.ReplaceAllString(text, "<del>$1</del>")
+	return text
+}
+
+// safeURL 判断链接协议是否允许
+func safeURL(url string) bool {
+	lower := strings.ToLower(url)
+	return strings.HasPrefix(lower, "http://") ||
+		strings.HasPrefix(lower, "https://") ||
+		strings.HasPrefix(lower, "mailto:")
+}
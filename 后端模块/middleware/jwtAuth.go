@@ -2,19 +2,23 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/models"
+	"taskmanager/token"
 )
 
-// jwtKey 应与controllers中的相同
-var jwtKey = []byte("your_secret_key")
+// 数据库连接，用于检查令牌是否已被吊销
+var db *gorm.DB
 
-// Claims 定义JWT的Claims结构
-type Claims struct {
-	UserID uint `json:"userId"`
-	jwt.StandardClaims
+// SetDB 设置中间件包的数据库连接
+func SetDB(database *gorm.DB) {
+	db = database
 }
 
 // JWTAuth JWT认证中间件
@@ -38,20 +42,86 @@ func JWTAuth() gin.HandlerFunc {
 
 		// 解析JWT令牌
 		tokenStr := parts[1]
-		claims := &Claims{}
-
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := token.Parse(tokenStr)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的认证令牌"})
 			c.Abort()
 			return
 		}
 
-		// 将用户ID存储在上下文中
+		// 检查令牌是否已被吊销（登出）
+		if claims.Id != "" && db != nil {
+			var count int
+			if err := db.Model(&models.RevokedToken{}).Where("jti = ?", claims.Id).Count(&count).Error; err != nil {
+				// 无法确认时拒绝请求，不能放行已吊销的令牌
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "校验认证令牌失败"})
+				c.Abort()
+				return
+			}
+			if count > 0 {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "认证令牌已失效"})
+				c.Abort()
+				return
+			}
+		}
+
+		// 确定当前工作区，未指定时使用个人工作区
+		var membership models.Membership
+		if db != nil {
+			var ok bool
+			if membership, ok = currentMembership(c, claims.UserID); !ok {
+				c.Abort()
+				return
+			}
+		}
+
+		// 将用户ID、令牌和工作区信息存储在上下文中
 		c.Set("userId", claims.UserID)
+		c.Set("tokenId", claims.Id)
+		c.Set("tokenExpiresAt", time.Unix(claims.ExpiresAt, 0))
+		c.Set("workspaceId", membership.WorkspaceID)
+		c.Set("workspaceRole", membership.Role)
 		c.Next()
 	}
 }
+
+// currentMembership 根据X-Workspace-ID请求头查找用户在当前工作区的成员记录
+// 未提供请求头时使用用户的个人工作区；失败时已写入错误响应
+func currentMembership(c *gin.Context, userID uint) (models.Membership, bool) {
+	var membership models.Membership
+	header := c.GetHeader("X-Workspace-ID")
+	if header == "" {
+		err := db.Table("memberships").
+			Select("memberships.*").
+			Joins("JOIN workspaces ON workspaces.id = memberships.workspace_id").
+			Where("memberships.user_id = ? AND workspaces.owner_id = ? AND workspaces.personal = ?", userID, userID, true).
+			Limit(1).
+			Scan(&membership).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "加载工作区失败"})
+			return membership, false
+		}
+		if membership.ID == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "个人工作区不存在"})
+			return membership, false
+		}
+		return membership, true
+	}
+
+	workspaceID, err := strconv.Atoi(header)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的工作区ID"})
+		return membership, false
+	}
+	// 只有确认不是成员时才返回403，其他数据库错误不能当作成员放行
+	err = db.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&membership).Error
+	if gorm.IsRecordNotFoundError(err) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "不是该工作区的成员"})
+		return membership, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "加载工作区失败"})
+		return membership, false
+	}
+	return membership, true
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// CalendarFeed 用户的日历订阅，通过带令牌的地址免登录读取
+// 只保存令牌摘要，令牌只在生成时返回一次
+type CalendarFeed struct {
+	ID            uint       `gorm:"primary_key" json:"id"`
+	UserID        uint       `gorm:"unique_index;not null" json:"userId"`
+	TokenHash     string     `gorm:"type:char(64);unique_index;not null" json:"-"`
+	LastFetchedAt *time.Time `json:"lastFetchedAt"` // 最近一次被日历客户端读取的时间
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// CalendarFeedResponse 日历订阅响应模型
+type CalendarFeedResponse struct {
+	Enabled       bool       `json:"enabled"`
+	Path          string     `json:"path,omitempty"` // 订阅地址的路径，只在生成令牌时返回
+	CreatedAt     *time.Time `json:"createdAt"`
+	LastFetchedAt *time.Time `json:"lastFetchedAt"`
+}
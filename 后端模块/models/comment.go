@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// Comment 任务评论
+type Comment struct {
+	ID        uint       `gorm:"primary_key" json:"id"`
+	TaskID    uint       `gorm:"index;not null" json:"taskId"`
+	UserID    uint       `gorm:"not null" json:"userId"`         // 评论作者
+	Body      string     `gorm:"type:text;not null" json:"body"` // Markdown正文
+	EditedAt  *time.Time `json:"editedAt"`                       // 最后编辑时间，未编辑过为空
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	DeletedAt *time.Time `sql:"index" json:"-"` // 删除时间
+}
+
+// CommentMention 评论中@提及的用户，用于后续通知
+type CommentMention struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	CommentID uint      `gorm:"index;not null" json:"commentId"`
+	UserID    uint      `gorm:"index;not null" json:"userId"` // 被提及的用户
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CommentResponse 评论响应模型
+type CommentResponse struct {
+	ID        uint          `json:"id"`
+	TaskID    uint          `json:"taskId"`
+	Author    UserSummary   `json:"author"`
+	Body      string        `json:"body"`     // Markdown原文
+	BodyHTML  string        `json:"bodyHtml"` // 渲染后的HTML，已转义，可直接显示
+	Mentions  []UserSummary `json:"mentions"` // 被提及的用户
+	EditedAt  *time.Time    `json:"editedAt"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+// CommentListResponse 评论列表响应模型
+type CommentListResponse struct {
+	Items    []CommentResponse `json:"items"`    // 当前页的评论，按时间正序
+	Total    int               `json:"total"`    // 评论总数
+	Page     int               `json:"page"`     // 当前页码
+	PageSize int               `json:"pageSize"` // 每页条数
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// TaskDependency 任务依赖，TaskID的任务被BlockerID的任务阻塞
+// 阻塞任务完成之前，被阻塞的任务不应开始或完成
+type TaskDependency struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	UserID    uint      `gorm:"index;not null" json:"userId"`                         // 两个任务的所有者
+	TaskID    uint      `gorm:"unique_index:idx_task_blocker;not null" json:"taskId"` // 被阻塞的任务
+	BlockerID uint      `gorm:"unique_index:idx_task_blocker;index;not null" json:"blockerId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// TaskDependencyResponse 任务依赖关系响应模型
+type TaskDependencyResponse struct {
+	BlockedBy []TaskResponse `json:"blockedBy"` // 阻塞该任务的任务
+	Blocks    []TaskResponse `json:"blocks"`    // 被该任务阻塞的任务
+}
+
+// TaskPlanItem 执行计划中的任务
+type TaskPlanItem struct {
+	TaskResponse
+	Level int `json:"level"` // 依赖层级，从0开始，同一层级的任务之间没有依赖，可以并行
+}
+
+// TaskPlanResponse 执行计划响应模型
+type TaskPlanResponse struct {
+	Items []TaskPlanItem `json:"items"` // 按依赖关系排序的未完成任务，阻塞任务总是排在被阻塞的任务之前
+}
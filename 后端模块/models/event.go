@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// 任务事件类型
+const (
+	TaskEventCreated   = "created"   // 创建
+	TaskEventUpdated   = "updated"   // 修改字段
+	TaskEventCompleted = "completed" // 标记完成
+	TaskEventReopened  = "reopened"  // 重新打开
+	TaskEventMoved     = "moved"     // 移动到其他父任务或项目
+	TaskEventDeleted   = "deleted"   // 移入回收站
+	TaskEventRestored  = "restored"  // 从回收站恢复
+)
+
+// TaskEvent 任务变更记录
+type TaskEvent struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	TaskID    uint      `gorm:"index;not null" json:"taskId"`
+	UserID    uint      `gorm:"not null" json:"userId"`         // 操作人
+	Action    string    `gorm:"size:20;not null" json:"action"` // 事件类型
+	Changes   string    `gorm:"type:text" json:"-"`             // 字段变更，FieldChange数组的JSON
+	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+}
+
+// FieldChange 单个字段的变更
+type FieldChange struct {
+	Field string      `json:"field"` // 字段名，与任务响应中的字段名一致
+	Old   interface{} `json:"old"`   // 修改前的值
+	New   interface{} `json:"new"`   // 修改后的值
+}
+
+// TaskEventResponse 任务变更记录响应模型
+type TaskEventResponse struct {
+	ID        uint          `json:"id"`
+	TaskID    uint          `json:"taskId"`
+	Action    string        `json:"action"`
+	Actor     UserSummary   `json:"actor"` // 操作人
+	Changes   []FieldChange `json:"changes"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+// TaskHistoryResponse 任务变更历史响应模型
+type TaskHistoryResponse struct {
+	Items    []TaskEventResponse `json:"items"`    // 当前页的记录，按时间倒序
+	Total    int                 `json:"total"`    // 记录总数
+	Page     int                 `json:"page"`     // 当前页码
+	PageSize int                 `json:"pageSize"` // 每页条数
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// File 文件元数据模型
+// 对象本身存放在MinIO中，这里记录归属、原始文件名等信息
+type File struct {
+	gorm.Model
+	UserID       uint   `gorm:"index;not null" json:"userId"`                // 文件所有者
+	WorkspaceID  uint   `gorm:"index;not null;default:0" json:"workspaceId"` // 上传时所在的工作区
+	OriginalName string `gorm:"size:255;not null" json:"originalName"`       // 上传时的原始文件名
+	ObjectKey    string `gorm:"size:255;unique_index;not null" json:"-"`     // MinIO中的对象键
+	Size         int64  `json:"size"`                                        // 文件大小（字节）
+	ContentType  string `gorm:"size:100" json:"contentType"`                 // 内容类型
+	Checksum     string `gorm:"type:char(64)" json:"checksum"`               // SHA-256校验和
+	TaskID       *uint  `gorm:"index" json:"taskId"`                         // 直接上传到的任务，该任务被彻底删除时文件随之删除
+}
+
+// FileResponse 文件响应结构
+type FileResponse struct {
+	ID       uint   `json:"id"`       // 文件ID
+	FileName string `json:"fileName"` // 原始文件名
+	FileURL  string `json:"fileUrl"`  // 文件访问URL（限时有效的预签名链接）
+	FileSize int64  `json:"fileSize"` // 文件大小（字节）
+	FileType string `json:"fileType"` // 文件类型
+	UploadAt string `json:"uploadAt"` // 上传时间
+}
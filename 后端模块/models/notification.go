@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID        uint       `gorm:"primary_key" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"userId"` // 接收通知的用户
+	ActorID   *uint      `json:"actorId"`                      // 触发通知的用户，系统通知为空
+	Type      string     `gorm:"size:20;not null" json:"type"`
+	TaskID    *uint      `gorm:"index" json:"taskId"`
+	Title     string     `gorm:"size:200;not null" json:"title"`
+	Body      string     `gorm:"type:text" json:"body"`
+	ReadAt    *time.Time `json:"readAt"` // 已读时间，未读为空
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// 通知类型
+const (
+	NotificationAssigned = "assigned" // 被设为任务负责人
+	NotificationComment  = "comment"  // 创建或负责的任务有新评论
+	NotificationMention  = "mention"  // 在评论中被@提及
+	NotificationReminder = "reminder" // 任务到期提醒
+)
+
+// NotificationResponse 通知响应模型
+type NotificationResponse struct {
+	ID        uint         `json:"id"`
+	Type      string       `json:"type"`
+	TaskID    *uint        `json:"taskId"`
+	Actor     *UserSummary `json:"actor"` // 触发通知的用户，系统通知为空
+	Title     string       `json:"title"`
+	Body      string       `json:"body"`
+	Read      bool         `json:"read"`
+	ReadAt    *time.Time   `json:"readAt"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+// NotificationListResponse 通知列表响应模型
+type NotificationListResponse struct {
+	Items    []NotificationResponse `json:"items"`    // 当前页的通知，按时间倒序
+	Total    int                    `json:"total"`    // 符合条件的通知总数
+	Unread   int                    `json:"unread"`   // 全部未读通知数
+	Page     int                    `json:"page"`     // 当前页码
+	PageSize int                    `json:"pageSize"` // 每页条数
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Project 项目模型（任务清单）
+type Project struct {
+	gorm.Model
+	UserID      uint   `gorm:"index;not null" json:"userId"`
+	WorkspaceID uint   `gorm:"index;not null;default:0" json:"workspaceId"` // 所属工作区
+	Name        string `gorm:"size:100;not null" json:"name"`
+	Description string `json:"description"`
+	Color       string `gorm:"size:7;default:'#409EFF'" json:"color"`
+	Archived    bool   `gorm:"default:false" json:"archived"`
+	SortOrder   int    `gorm:"default:0" json:"sortOrder"` // 排序，越小越靠前
+}
+
+// ProjectResponse 项目响应模型
+type ProjectResponse struct {
+	ID             uint      `json:"id"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	Color          string    `json:"color"`
+	Archived       bool      `json:"archived"`
+	SortOrder      int       `json:"sortOrder"`
+	TaskCount      int       `json:"taskCount"`      // 任务总数
+	CompletedCount int       `json:"completedCount"` // 已完成任务数
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// TaskReminder 任务的到期提醒，在截止日期前OffsetMinutes分钟触发
+type TaskReminder struct {
+	ID            uint       `gorm:"primary_key" json:"id"`
+	TaskID        uint       `gorm:"unique_index:idx_task_reminder_offset;not null" json:"taskId"`
+	OffsetMinutes int        `gorm:"unique_index:idx_task_reminder_offset;not null" json:"offset"` // 提前的分钟数
+	RemindAt      *time.Time `gorm:"index" json:"remindAt"`                                        // 触发时间，任务没有截止日期时为空
+	SentAt        *time.Time `json:"sentAt"`                                                       // 全部投递完成的时间，尚未完成时为空
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+// ReminderDelivery 提醒通过某个渠道发给某个用户的投递状态
+// 发送成功后才记录DeliveredAt，发送后进程退出会重新投递，保证至少一次
+type ReminderDelivery struct {
+	ID            uint       `gorm:"primary_key" json:"id"`
+	ReminderID    uint       `gorm:"unique_index:idx_reminder_delivery;not null" json:"reminderId"`
+	UserID        uint       `gorm:"unique_index:idx_reminder_delivery;not null" json:"userId"`
+	Channel       string     `gorm:"unique_index:idx_reminder_delivery;size:20;not null" json:"channel"` // inbox、email或webhook
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`                                 // 已尝试次数
+	LastError     string     `gorm:"size:255" json:"lastError"`                                          // 最近一次失败的原因
+	NextAttemptAt *time.Time `json:"nextAttemptAt"`                                                      // 失败后下一次重试的时间，为空表示可以立即发送
+	DeliveredAt   *time.Time `json:"deliveredAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// TaskReminderResponse 任务提醒响应模型
+type TaskReminderResponse struct {
+	Offset   int        `json:"offset"`   // 提前的分钟数
+	RemindAt *time.Time `json:"remindAt"` // 触发时间，任务没有截止日期时为空
+	SentAt   *time.Time `json:"sentAt"`   // 已发送的时间，尚未发送时为空
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// 任务共享权限
+const (
+	SharePermissionView = "view" // 查看任务、评论和附件，可以发表评论
+	SharePermissionEdit = "edit" // 在查看的基础上修改任务内容、状态、附件、依赖和负责人
+)
+
+// TaskShare 任务共享记录，将任务以指定权限共享给其他用户
+type TaskShare struct {
+	ID         uint      `gorm:"primary_key" json:"id"`
+	TaskID     uint      `gorm:"unique_index:idx_task_share_user;not null" json:"taskId"`
+	UserID     uint      `gorm:"unique_index:idx_task_share_user;index;not null" json:"userId"` // 被共享的用户
+	Permission string    `gorm:"size:10;not null" json:"permission"`                            // view或edit
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// TaskAssignee 任务负责人，负责人拥有任务的编辑权限
+type TaskAssignee struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	TaskID    uint      `gorm:"unique_index:idx_task_assignee_user;not null" json:"taskId"`
+	UserID    uint      `gorm:"unique_index:idx_task_assignee_user;index;not null" json:"userId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// TaskShareResponse 任务共享响应模型
+type TaskShareResponse struct {
+	User       UserSummary `json:"user"`
+	Permission string      `json:"permission"`
+	CreatedAt  time.Time   `json:"createdAt"`
+}
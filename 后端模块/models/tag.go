@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// Tag 标签模型
+// 标签归属于用户，同一用户下名称唯一
+type Tag struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	UserID    uint      `gorm:"unique_index:idx_tag_user_name;not null" json:"userId"`
+	Name      string    `gorm:"size:50;unique_index:idx_tag_user_name;not null" json:"name"`
+	Color     string    `gorm:"size:7;default:'#409EFF'" json:"color"` // 十六进制颜色，如 #409EFF
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TagResponse 任务中返回的标签信息
+type TagResponse struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
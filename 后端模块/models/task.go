@@ -19,23 +19,102 @@ const (
 // Task 任务模型
 type Task struct {
 	gorm.Model
-	Title       string     `gorm:"not null" json:"title"`
-	Description string     `json:"description"`
-	Completed   bool       `gorm:"default:false" json:"completed"`
-	Priority    Priority   `gorm:"type:varchar(10);default:'medium'" json:"priority"`
-	DueDate     *time.Time `json:"dueDate"`
-	UserID      uint       `json:"userId"` // 关联到用户
+	Title            string     `gorm:"not null" json:"title"`
+	Description      string     `json:"description"`
+	Completed        bool       `gorm:"default:false" json:"completed"`                   // 是否完成，由status所属的状态分类决定
+	Status           string     `gorm:"size:50;index" json:"status"`                      // 工作流状态，对应WorkflowState.Key
+	Rank             int64      `gorm:"column:board_rank;not null;default:0" json:"rank"` // 看板列内的排序，越小越靠前（rank是MySQL保留字）
+	Priority         Priority   `gorm:"type:varchar(10);default:'medium'" json:"priority"`
+	DueDate          *time.Time `json:"dueDate"`
+	UserID           uint       `json:"userId"`                                      // 关联到用户
+	WorkspaceID      uint       `gorm:"index;not null;default:0" json:"workspaceId"` // 所属工作区
+	ParentID         *uint      `gorm:"index" json:"parentId"`                       // 父任务ID，为空表示顶层任务
+	ProjectID        *uint      `gorm:"index" json:"projectId"`                      // 所属项目ID，为空表示未归入项目
+	Recurrence       string     `gorm:"size:255" json:"recurrence"`                  // 重复规则（RRULE子集），为空表示不重复
+	Occurrence       int        `gorm:"default:1" json:"occurrence"`                 // 重复任务的第几次，从1开始
+	NextOccurrenceID *uint      `json:"nextOccurrenceId"`                            // 完成后生成的下一次任务ID
+	Version          int        `gorm:"not null;default:1" json:"version"`           // 版本号，每次修改自增，用于乐观锁
+	DeleteBatch      string     `gorm:"size:36;index" json:"-"`                      // 删除批次，同一次删除移入回收站的任务相同，恢复时清空
+	Tags             []Tag      `gorm:"many2many:task_tags;association_autoupdate:false;association_autocreate:false" json:"tags"`
+	Attachments      []File     `gorm:"many2many:task_attachments;association_autoupdate:false;association_autocreate:false" json:"attachments"`
 }
 
 // TaskResponse 任务响应模型
 type TaskResponse struct {
-	ID          uint       `json:"id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Completed   bool       `json:"completed"`
-	Priority    Priority   `json:"priority"`
-	DueDate     *time.Time `json:"dueDate"`
-	UserID      uint       `json:"userId"`
-	CreatedAt   time.Time  `json:"createdAt"`
-	UpdatedAt   time.Time  `json:"updatedAt"`
+	ID               uint           `json:"id"`
+	Title            string         `json:"title"`
+	Description      string         `json:"description"`
+	Completed        bool           `json:"completed"`
+	Status           string         `json:"status"`
+	Rank             int64          `json:"rank"`
+	Priority         Priority       `json:"priority"`
+	DueDate          *time.Time     `json:"dueDate"`
+	UserID           uint           `json:"userId"`
+	WorkspaceID      uint           `json:"workspaceId"`
+	ParentID         *uint          `json:"parentId"`
+	ProjectID        *uint          `json:"projectId"`
+	Recurrence       string         `json:"recurrence"`
+	Occurrence       int            `json:"occurrence"`
+	NextOccurrenceID *uint          `json:"nextOccurrenceId"`
+	Version          int            `json:"version"`
+	Tags             []TagResponse  `json:"tags"`
+	Attachments      []FileResponse `json:"attachments"` // 附件
+	Assignees        []UserSummary  `json:"assignees"`   // 负责人
+	CreatedAt        time.Time      `json:"createdAt"`
+	UpdatedAt        time.Time      `json:"updatedAt"`
+
+	ChildCount          int            `json:"childCount"`           // 直接子任务数
+	CompletedChildCount int            `json:"completedChildCount"`  // 已完成的直接子任务数
+	Progress            int            `json:"progress"`             // 完成百分比（0-100）
+	BlockedBy           []uint         `json:"blockedBy"`            // 阻塞该任务的任务ID
+	Blocks              []uint         `json:"blocks"`               // 被该任务阻塞的任务ID
+	Blocked             bool           `json:"blocked"`              // 任务未完成且有未完成的阻塞任务
+	Permission          string         `json:"permission,omitempty"` // 当前用户的权限：owner、edit或view，仅在任务列表、搜索结果和单个任务中返回
+	Children            []TaskResponse `json:"children,omitempty"`   // 子任务，仅在树形返回时填充
+}
+
+// TaskListResponse 任务列表响应模型
+type TaskListResponse struct {
+	Items      []TaskResponse `json:"items"`                // 当前页的任务
+	Total      int            `json:"total"`                // 符合筛选条件的任务总数
+	Page       int            `json:"page,omitempty"`       // 当前页码，使用游标分页时省略
+	PageSize   int            `json:"pageSize"`             // 每页条数
+	NextCursor string         `json:"nextCursor,omitempty"` // 下一页游标，没有下一页时省略
+}
+
+// TaskSearchResult 任务搜索结果
+type TaskSearchResult struct {
+	TaskResponse
+	Score      float64        `json:"score"`      // 相关度，越大越相关
+	Highlights TaskHighlights `json:"highlights"` // 高亮片段
+}
+
+// TaskHighlights 搜索高亮片段，已做HTML转义，匹配部分用<mark>标签包裹
+type TaskHighlights struct {
+	Title       string `json:"title"`       // 完整标题
+	Description string `json:"description"` // 描述中匹配位置附近的片段
+}
+
+// TaskSearchResponse 任务搜索响应模型
+type TaskSearchResponse struct {
+	Items    []TaskSearchResult `json:"items"`    // 当前页的搜索结果，按相关度排序
+	Total    int                `json:"total"`    // 匹配的任务总数
+	Page     int                `json:"page"`     // 当前页码
+	PageSize int                `json:"pageSize"` // 每页条数
+}
+
+// TrashTaskResponse 回收站中的任务
+type TrashTaskResponse struct {
+	TaskResponse
+	DeletedAt           time.Time  `json:"deletedAt"`           // 删除时间
+	PurgeAt             *time.Time `json:"purgeAt"`             // 预计永久删除的时间，未开启自动清理时为null
+	DeletedSubtaskCount int        `json:"deletedSubtaskCount"` // 随该任务一起删除的子任务数
+}
+
+// TrashListResponse 回收站列表响应模型
+type TrashListResponse struct {
+	Items    []TrashTaskResponse `json:"items"`    // 当前页的任务，按删除时间倒序
+	Total    int                 `json:"total"`    // 回收站中的条目总数
+	Page     int                 `json:"page"`     // 当前页码
+	PageSize int                 `json:"pageSize"` // 每页条数
 }
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// RefreshToken 刷新令牌模型
+// 数据库中只保存令牌的SHA-256摘要，原始令牌仅在签发时返回给客户端
+type RefreshToken struct {
+	gorm.Model
+	UserID     uint       `gorm:"index;not null" json:"userId"`
+	TokenHash  string     `gorm:"type:char(64);unique_index;not null" json:"-"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expiresAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	ReplacedBy string     `gorm:"type:char(64)" json:"-"` // 轮换后新令牌的摘要
+}
+
+// IsActive 判断刷新令牌是否仍然可用
+func (t *RefreshToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// RevokedToken 已吊销的访问令牌
+// 以JWT的jti为键记录，过期后即可清理
+type RevokedToken struct {
+	ID        uint      `gorm:"primary_key"`
+	JTI       string    `gorm:"type:varchar(64);unique_index;not null"`
+	UserID    uint      `gorm:"index"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+	CreatedAt time.Time
+}
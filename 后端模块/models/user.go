@@ -12,3 +12,9 @@ type User struct {
 	Email      string `gorm:"size:100" json:"email"`
 	AvatarPath string `gorm:"size:255" json:"avatar_path"` // 头像存储路径
 }
+
+// UserSummary 在其他资源中引用用户时返回的简要信息
+type UserSummary struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
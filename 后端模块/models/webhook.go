@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook 用户在工作区中订阅的外发Webhook
+// 只推送该用户能看到的任务和自己的文件的事件
+type Webhook struct {
+	ID          uint      `gorm:"primary_key" json:"id"`
+	UserID      uint      `gorm:"index;not null" json:"userId"`
+	WorkspaceID uint      `gorm:"index;not null" json:"workspaceId"`
+	URL         string    `gorm:"size:500;not null" json:"url"`
+	Secret      string    `gorm:"size:100;not null" json:"-"` // 签名密钥，只在创建和更换时返回
+	Events      string    `gorm:"size:500;not null" json:"-"` // 订阅的事件类型，逗号分隔
+	Active      bool      `gorm:"not null" json:"active"`     // 停用后不再产生新的投递
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// 投递状态
+const (
+	DeliveryPending   = "pending"   // 等待发送或重试
+	DeliveryDelivered = "delivered" // 已发送成功
+	DeliveryFailed    = "failed"    // 超过最大尝试次数或Webhook已停用，不再重试
+)
+
+// WebhookDelivery Webhook的一次投递及其发送状态，作为投递日志保留
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primary_key" json:"id"`
+	WebhookID      uint       `gorm:"index;not null" json:"webhookId"`
+	Event          string     `gorm:"size:50;not null" json:"event"`
+	Payload        string     `gorm:"type:text" json:"payload"`
+	Status         string     `gorm:"size:20;index;not null" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"` // 已尝试次数
+	ResponseStatus int        `json:"responseStatus"`                     // 最近一次响应的状态码，未收到响应时为0
+	LastError      string     `gorm:"size:255" json:"lastError"`          // 最近一次失败的原因
+	NextAttemptAt  time.Time  `gorm:"index" json:"nextAttemptAt"`         // 下一次发送的时间
+	DeliveredAt    *time.Time `json:"deliveredAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// WebhookResponse Webhook响应模型
+type WebhookResponse struct {
+	ID        uint      `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"` // 订阅的事件类型
+	Active    bool      `json:"active"`
+	Secret    string    `json:"secret,omitempty"` // 签名密钥，只在创建和更换时返回
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// WebhookDeliveryResponse 投递日志响应模型
+type WebhookDeliveryResponse struct {
+	ID             uint            `json:"id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"` // 发送的请求体
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"responseStatus"`
+	LastError      string          `json:"lastError"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt"` // 下一次重试的时间，不再重试时为空
+	DeliveredAt    *time.Time      `json:"deliveredAt"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+// WebhookDeliveryListResponse 投递日志列表响应模型
+type WebhookDeliveryListResponse struct {
+	Items    []WebhookDeliveryResponse `json:"items"`
+	Total    int                       `json:"total"`
+	Page     int                       `json:"page"`
+	PageSize int                       `json:"pageSize"`
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// 工作流状态分类，done分类的状态视为已完成
+const (
+	StatusCategoryTodo       = "todo"
+	StatusCategoryInProgress = "in_progress"
+	StatusCategoryDone       = "done"
+)
+
+// WorkflowState 工作流状态（看板的一列）
+// ProjectID为空的状态组成用户的默认工作流，项目没有自定义工作流时使用默认工作流
+type WorkflowState struct {
+	ID          uint      `gorm:"primary_key" json:"id"`
+	UserID      uint      `gorm:"index;not null" json:"userId"`
+	ProjectID   *uint     `gorm:"index" json:"projectId"`                       // 所属项目ID，为空表示用户的默认工作流
+	Key         string    `gorm:"column:state_key;size:50;not null" json:"key"` // 状态标识，即任务的status字段（key是MySQL保留字）
+	Name        string    `gorm:"size:50;not null" json:"name"`                 // 显示名称
+	Color       string    `gorm:"size:7" json:"color"`
+	Category    string    `gorm:"size:20;not null" json:"category"` // 状态分类：todo、in_progress、done
+	SortOrder   int       `gorm:"default:0" json:"sortOrder"`       // 列顺序，越小越靠前
+	Transitions string    `gorm:"size:1000" json:"-"`               // 允许流转到的状态，逗号分隔，为空表示不限制
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Done 判断该状态是否表示任务已完成
+func (s WorkflowState) Done() bool {
+	return s.Category == StatusCategoryDone
+}
+
+// TransitionKeys 返回允许流转到的状态，为空表示可以流转到任意状态
+func (s WorkflowState) TransitionKeys() []string {
+	if s.Transitions == "" {
+		return []string{}
+	}
+	return strings.Split(s.Transitions, ",")
+}
+
+// CanTransition 判断任务能否从该状态流转到目标状态，停留在原状态总是允许
+func (s WorkflowState) CanTransition(to string) bool {
+	if s.Transitions == "" || to == s.Key {
+		return true
+	}
+	for _, key := range s.TransitionKeys() {
+		if key == to {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkflowStateResponse 工作流状态响应模型
+type WorkflowStateResponse struct {
+	Key         string   `json:"key"`
+	Name        string   `json:"name"`
+	Color       string   `json:"color"`
+	Category    string   `json:"category"`
+	Transitions []string `json:"transitions"` // 允许流转到的状态，空数组表示不限制
+}
+
+// WorkflowResponse 工作流响应模型
+type WorkflowResponse struct {
+	ProjectID *uint                   `json:"projectId"` // 查询的项目ID，默认工作流为null
+	Custom    bool                    `json:"custom"`    // 项目是否使用自定义工作流，为false时使用默认工作流
+	States    []WorkflowStateResponse `json:"states"`    // 按列顺序排列的状态
+}
+
+// BoardColumn 看板中的一列
+type BoardColumn struct {
+	State WorkflowStateResponse `json:"state"`
+	Tasks []TaskResponse        `json:"tasks"` // 列中的任务，按rank排序
+	Total int                   `json:"total"` // 列中的任务总数，可能多于返回的任务数
+}
+
+// BoardResponse 看板响应模型
+type BoardResponse struct {
+	ProjectID *uint         `json:"projectId"`
+	Custom    bool          `json:"custom"`
+	Columns   []BoardColumn `json:"columns"`
+}
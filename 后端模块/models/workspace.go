@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+)
+
+// 工作区成员角色
+const (
+	RoleOwner  = "owner"  // 创建者，管理工作区和全部成员
+	RoleAdmin  = "admin"  // 管理员，邀请和管理成员
+	RoleMember = "member" // 普通成员，创建任务、项目和文件
+	RoleGuest  = "guest"  // 访客，只能访问共享给自己或自己负责的任务
+)
+
+// 邀请状态
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+)
+
+// Workspace 工作区，任务、项目和文件都属于某个工作区，不同工作区之间的数据互相隔离
+type Workspace struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	OwnerID   uint      `gorm:"index;not null" json:"ownerId"`
+	Personal  bool      `gorm:"default:false" json:"personal"` // 注册时自动创建的个人工作区，未指定工作区时使用
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Membership 工作区成员
+type Membership struct {
+	ID          uint      `gorm:"primary_key" json:"id"`
+	WorkspaceID uint      `gorm:"unique_index:idx_workspace_member;not null" json:"workspaceId"`
+	UserID      uint      `gorm:"unique_index:idx_workspace_member;index;not null" json:"userId"`
+	Role        string    `gorm:"size:10;not null" json:"role"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Invitation 工作区邀请
+// 数据库中只保存邀请令牌的SHA-256摘要，原始令牌仅在创建邀请时返回
+type Invitation struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	WorkspaceID uint       `gorm:"index;not null" json:"workspaceId"`
+	InviterID   uint       `gorm:"not null" json:"inviterId"`
+	InviteeID   *uint      `gorm:"index" json:"inviteeId"`                       // 按用户名邀请或邮箱对应已注册用户时填写
+	Email       string     `gorm:"size:100;index" json:"email"`                  // 按邮箱邀请时填写
+	Role        string     `gorm:"size:10;not null" json:"role"`                 // 接受后获得的角色
+	TokenHash   string     `gorm:"type:char(64);unique_index;not null" json:"-"` // 邀请令牌摘要
+	Status      string     `gorm:"size:10;not null" json:"status"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expiresAt"`
+	RespondedAt *time.Time `json:"respondedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// WorkspaceResponse 工作区响应模型
+type WorkspaceResponse struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	OwnerID     uint      `json:"ownerId"`
+	Personal    bool      `json:"personal"`
+	Role        string    `json:"role"`        // 当前用户在工作区中的角色
+	MemberCount int       `json:"memberCount"` // 成员数
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// MemberResponse 工作区成员响应模型
+type MemberResponse struct {
+	User     UserSummary `json:"user"`
+	Role     string      `json:"role"`
+	JoinedAt time.Time   `json:"joinedAt"`
+}
+
+// InvitationResponse 邀请响应模型
+type InvitationResponse struct {
+	ID        uint         `json:"id"`
+	Workspace WorkspaceRef `json:"workspace"`
+	Inviter   UserSummary  `json:"inviter"`
+	Invitee   *UserSummary `json:"invitee"` // 按邮箱邀请未注册用户时为null
+	Email     string       `json:"email"`
+	Role      string       `json:"role"`
+	Status    string       `json:"status"`
+	ExpiresAt time.Time    `json:"expiresAt"`
+	CreatedAt time.Time    `json:"createdAt"`
+	Token     string       `json:"token,omitempty"` // 邀请令牌，仅在创建邀请时返回
+}
+
+// WorkspaceRef 在其他资源中引用工作区时返回的简要信息
+type WorkspaceRef struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
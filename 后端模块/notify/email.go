@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Email 通过SMTP发送邮件的渠道
+// 未设置Username时不进行认证，可以直接对接本地的MailHog等测试服务器
+type Email struct {
+	Addr     string // SMTP服务器地址，如 smtp.example.com:587
+	From     string // 发件人地址
+	Username string
+	Password string
+}
+
+// Name 渠道名称
+func (e *Email) Name() string {
+	return "email"
+}
+
+// Send 发送纯文本邮件，接收者没有邮箱时返回ErrNoAddress
+func (e *Email) Send(msg Message) error {
+	if msg.Email == "" {
+		return ErrNoAddress
+	}
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		host, _, err := net.SplitHostPort(e.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", e.From)
+	fmt.Fprintf(&body, "To: %s\r\n", msg.Email)
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Title))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	body.WriteString("\r\n")
+
+	return smtp.SendMail(e.Addr, auth, e.From, []string{msg.Email}, []byte(body.String()))
+}
@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTP 启动一个只接收邮件的SMTP服务器，返回地址和收到邮件内容的通道
+func fakeSMTP(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	mails := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 fake\r\n")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					mails <- data.String()
+					fmt.Fprint(conn, "250 ok\r\n")
+				} else {
+					data.WriteString(line)
+				}
+				continue
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case cmd == "DATA":
+				inData = true
+				fmt.Fprint(conn, "354 go ahead\r\n")
+			case cmd == "QUIT":
+				fmt.Fprint(conn, "221 bye\r\n")
+				return
+			default:
+				fmt.Fprint(conn, "250 ok\r\n")
+			}
+		}
+	}()
+	return l.Addr().String(), mails
+}
+
+func TestEmailSend(t *testing.T) {
+	addr, mails := fakeSMTP(t)
+	email := &Email{Addr: addr, From: "taskmanager@localhost"}
+
+	err := email.Send(Message{
+		Email: "bob@example.com",
+		Title: "任务即将到期：写报告",
+		Body:  "第一行\n第二行",
+	})
+	if err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	mail := <-mails
+	for _, want := range []string{
+		"From: taskmanager@localhost\r\n",
+		"To: bob@example.com\r\n",
+		"Subject: =?utf-8?q?",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"\r\n\r\n第一行\r\n第二行\r\n",
+	} {
+		if !strings.Contains(mail, want) {
+			t.Errorf("邮件中缺少 %q:\n%s", want, mail)
+		}
+	}
+}
+
+func TestEmailSendNoAddress(t *testing.T) {
+	email := &Email{Addr: "127.0.0.1:1", From: "taskmanager@localhost"}
+	if err := email.Send(Message{Title: "标题"}); err != ErrNoAddress {
+		t.Fatalf("没有邮箱时应返回ErrNoAddress，实际为 %v", err)
+	}
+}
+
+func TestEmailSendUnreachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	email := &Email{Addr: addr, From: "taskmanager@localhost"}
+	if err := email.Send(Message{Email: "bob@example.com"}); err == nil || err == ErrNoAddress {
+		t.Fatalf("服务器不可达时应返回可重试的错误，实际为 %v", err)
+	}
+}
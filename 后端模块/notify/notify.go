@@ -0,0 +1,28 @@
+package notify
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNoAddress 接收者在该渠道没有可用的地址（如未设置邮箱），不需要重试
+var ErrNoAddress = errors.New("接收者没有可用的地址")
+
+// Message 发给一个用户的通知
+type Message struct {
+	Event    string    // 事件类型，如 task.reminder
+	UserID   uint      // 接收者
+	Username string    // 接收者用户名
+	Email    string    // 接收者邮箱，可能为空
+	TaskID   uint      // 相关任务
+	Title    string    // 通知标题
+	Body     string    // 通知正文，纯文本
+	DueDate  time.Time // 任务截止时间
+}
+
+// Channel 通知的投递渠道
+// Send返回nil表示已投递成功，返回其他错误时调用方负责重试
+type Channel interface {
+	Name() string
+	Send(msg Message) error
+}
@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Webhook 将通知以JSON形式POST到指定URL的渠道
+type Webhook struct {
+	URL    string
+	Client *http.Client // 为空时使用10秒超时的默认客户端
+}
+
+// webhookPayload 发送给Webhook的请求体
+type webhookPayload struct {
+	Event    string    `json:"event"`
+	UserID   uint      `json:"userId"`
+	Username string    `json:"username"`
+	TaskID   uint      `json:"taskId"`
+	Title    string    `json:"title"`
+	Body     string    `json:"body"`
+	DueDate  time.Time `json:"dueDate"`
+}
+
+// Name 渠道名称
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+// Send 发送通知，响应状态码不是2xx时返回错误
+func (w *Webhook) Send(msg Message) error {
+	payload, err := json.Marshal(webhookPayload{
+		Event:    msg.Event,
+		UserID:   msg.UserID,
+		Username: msg.Username,
+		TaskID:   msg.TaskID,
+		Title:    msg.Title,
+		Body:     msg.Body,
+		DueDate:  msg.DueDate,
+	})
+	if err != nil {
+		return err
+	}
+
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(w.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
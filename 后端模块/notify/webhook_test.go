@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookSend(t *testing.T) {
+	var got webhookPayload
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("解析请求体失败: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	due := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	msg := Message{
+		Event:    "task.reminder",
+		UserID:   2,
+		Username: "bob",
+		Email:    "bob@example.com",
+		TaskID:   7,
+		Title:    "任务即将到期：写报告",
+		Body:     "任务「写报告」将于 2026-10-18 09:00 到期。",
+		DueDate:  due,
+	}
+	if err := (&Webhook{URL: server.URL}).Send(msg); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	want := webhookPayload{
+		Event:    msg.Event,
+		UserID:   msg.UserID,
+		Username: msg.Username,
+		TaskID:   msg.TaskID,
+		Title:    msg.Title,
+		Body:     msg.Body,
+		DueDate:  due,
+	}
+	if !got.DueDate.Equal(want.DueDate) {
+		t.Errorf("dueDate = %v, 期望 %v", got.DueDate, want.DueDate)
+	}
+	got.DueDate = want.DueDate
+	if got != want {
+		t.Errorf("请求体 = %+v, 期望 %+v", got, want)
+	}
+}
+
+func TestWebhookSendStatus(t *testing.T) {
+	tests := []struct {
+		status  int
+		wantErr bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusAccepted, false},
+		{http.StatusMovedPermanently, true},
+		{http.StatusBadRequest, true},
+		{http.StatusInternalServerError, true},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		// 不跟随重定向，3xx按失败处理
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		err := (&Webhook{URL: server.URL, Client: client}).Send(Message{Event: "task.reminder"})
+		server.Close()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("状态码 %d: err = %v, 期望出错 %v", tt.status, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWebhookSendUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	if err := (&Webhook{URL: url}).Send(Message{Event: "task.reminder"}); err == nil {
+		t.Fatal("服务器不可达时应返回错误")
+	}
+}
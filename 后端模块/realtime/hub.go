@@ -0,0 +1,163 @@
+package realtime
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 每个订阅者的事件缓冲，写满时断开该订阅，由客户端带着最后的事件ID重连
+const subscriptionBuffer = 64
+
+// Event 推送给客户端的事件
+type Event struct {
+	ID          string      // 事件ID，形如 "<启动标识>-<序号>"，用于断线续传
+	Type        string      // 事件类型，如 task.updated
+	WorkspaceID uint        // 事件所属的工作区
+	UserIDs     []uint      // 接收事件的用户
+	Data        interface{} // 事件内容，推送时序列化为JSON
+
+	seq uint64
+}
+
+// visibleTo 判断事件是否推送给指定工作区中的用户
+func (e Event) visibleTo(userID, workspaceID uint) bool {
+	if e.WorkspaceID != workspaceID {
+		return false
+	}
+	for _, id := range e.UserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription 一个客户端连接的订阅
+type Subscription struct {
+	C <-chan Event // 新事件，订阅被断开时关闭
+
+	ch          chan Event
+	userID      uint
+	workspaceID uint
+}
+
+// Hub 事件中心，保存最近的事件供断线续传
+type Hub struct {
+	mu      sync.Mutex
+	boot    string // 启动标识，服务重启后旧的事件ID不能续传
+	seq     uint64 // 最后一个事件的序号
+	backlog []Event
+	next    int // backlog写满后下一个覆盖的位置
+	subs    map[*Subscription]struct{}
+}
+
+// NewHub 创建事件中心，size为保留的最近事件数
+func NewHub(size int) *Hub {
+	if size < 1 {
+		size = 1
+	}
+	return &Hub{
+		boot:    strconv.FormatInt(time.Now().UnixNano(), 36),
+		backlog: make([]Event, 0, size),
+		subs:    make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish 发布事件，推送给在线的订阅者并保存到最近事件中
+func (h *Hub) Publish(eventType string, workspaceID uint, userIDs []uint, data interface{}) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	event := Event{
+		ID:          h.eventID(h.seq),
+		Type:        eventType,
+		WorkspaceID: workspaceID,
+		UserIDs:     userIDs,
+		Data:        data,
+		seq:         h.seq,
+	}
+	if len(h.backlog) < cap(h.backlog) {
+		h.backlog = append(h.backlog, event)
+	} else {
+		h.backlog[h.next] = event
+		h.next = (h.next + 1) % len(h.backlog)
+	}
+
+	for sub := range h.subs {
+		if !event.visibleTo(sub.userID, sub.workspaceID) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			// 订阅者处理不及时，断开后由客户端续传
+			h.remove(sub)
+		}
+	}
+}
+
+// Subscribe 订阅用户在工作区中的事件
+// lastEventID不为空时返回其后错过的事件；无法续传（事件已不在保留范围内或服务已重启）时resumed为false，
+// 客户端需要重新加载数据。latestID为当前最后一个事件的ID，不能续传时客户端应从这里开始记录
+func (h *Hub) Subscribe(userID, workspaceID uint, lastEventID string) (sub *Subscription, missed []Event, latestID string, resumed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Event, subscriptionBuffer)
+	sub = &Subscription{C: ch, ch: ch, userID: userID, workspaceID: workspaceID}
+	h.subs[sub] = struct{}{}
+	latestID = h.eventID(h.seq)
+
+	seq, ok := h.parseEventID(lastEventID)
+	if !ok || seq > h.seq || seq+uint64(len(h.backlog)) < h.seq {
+		return sub, nil, latestID, false
+	}
+	for i := range h.backlog {
+		event := h.backlog[(h.next+i)%len(h.backlog)]
+		if event.seq > seq && event.visibleTo(userID, workspaceID) {
+			missed = append(missed, event)
+		}
+	}
+	return sub, missed, latestID, true
+}
+
+// Unsubscribe 取消订阅，可以重复调用
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(sub)
+}
+
+// remove 移除订阅并关闭其通道，调用方需持有锁
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// eventID 生成事件ID
+func (h *Hub) eventID(seq uint64) string {
+	return fmt.Sprintf("%s-%d", h.boot, seq)
+}
+
+// parseEventID 解析本次启动生成的事件ID，返回序号
+func (h *Hub) parseEventID(id string) (uint64, bool) {
+	boot, seq, found := strings.Cut(id, "-")
+	if !found || boot != h.boot {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,308 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency 重复频率
+type Frequency string
+
+// 支持的重复频率
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// untilLayout UNTIL参数的日期格式
+const untilLayout = "20060102"
+
+// 星期缩写与time.Weekday的对应关系
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Rule 重复规则，采用RFC 5545 RRULE的子集
+//
+// 示例：
+//   - FREQ=DAILY;INTERVAL=1                 每天
+//   - FREQ=WEEKLY;BYDAY=MO,WE,FR            每周一、三、五
+//   - FREQ=MONTHLY;BYMONTHDAY=-1            每月最后一天
+//   - FREQ=DAILY;INTERVAL=3;X-FROM=COMPLETION 完成后每隔3天
+//   - FREQ=WEEKLY;COUNT=10 / UNTIL=20251231 按次数或日期结束
+//
+// 与RFC 5545不同，MONTHLY规则的日期超出当月天数时不跳过该月，而是取当月最后一天：
+// 每月31日的规则在4月落在30日、在2月落在28日（闰年29日），之后的月份仍回到31日。
+// 导出到日历时按此转换，如 BYMONTHDAY=31 导出为 BYMONTHDAY=28,29,30,31;BYSETPOS=-1
+type Rule struct {
+	Freq           Frequency
+	Interval       int            // 间隔，默认为1
+	ByDay          []time.Weekday // 仅WEEKLY：在一周中的哪些天重复
+	ByMonthDay     int            // 仅MONTHLY：每月第几天，-1表示最后一天，0表示沿用截止日期的日；超出当月天数时取最后一天
+	Count          int            // 总次数，0表示不限
+	Until          *time.Time     // 截止日期（含当天），为空表示不限
+	FromCompletion bool           // 为true时从完成时间起算，而不是从截止日期起算
+}
+
+// Parse 解析重复规则字符串
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, errors.New("重复规则不能为空")
+	}
+
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("无效的重复规则片段: %s", part)
+		}
+		key, val := strings.ToUpper(strings.TrimSpace(kv[0])), strings.TrimSpace(kv[1])
+
+		switch key {
+		case "FREQ":
+			freq := Frequency(strings.ToUpper(val))
+			if freq != Daily && freq != Weekly && freq != Monthly {
+				return nil, fmt.Errorf("不支持的重复频率: %s", val)
+			}
+			rule.Freq = freq
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("无效的INTERVAL: %s", val)
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				day, ok := weekdayCodes[strings.ToUpper(strings.TrimSpace(code))]
+				if !ok {
+					return nil, fmt.Errorf("无效的BYDAY: %s", code)
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(val)
+			if err != nil || n == 0 || n < -1 || n > 31 {
+				return nil, fmt.Errorf("无效的BYMONTHDAY: %s", val)
+			}
+			rule.ByMonthDay = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("无效的COUNT: %s", val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			t, err := time.Parse(untilLayout, strings.SplitN(val, "T", 2)[0])
+			if err != nil {
+				return nil, fmt.Errorf("无效的UNTIL: %s", val)
+			}
+			rule.Until = &t
+		case "X-FROM":
+			if strings.ToUpper(val) != "COMPLETION" {
+				return nil, fmt.Errorf("无效的X-FROM: %s", val)
+			}
+			rule.FromCompletion = true
+		default:
+			return nil, fmt.Errorf("不支持的重复规则参数: %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("重复规则缺少FREQ")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, errors.New("COUNT和UNTIL不能同时使用")
+	}
+	if len(rule.ByDay) > 0 && (rule.Freq != Weekly || rule.FromCompletion) {
+		return nil, errors.New("BYDAY只能用于按截止日期重复的WEEKLY规则")
+	}
+	if rule.ByMonthDay != 0 && (rule.Freq != Monthly || rule.FromCompletion) {
+		return nil, errors.New("BYMONTHDAY只能用于按截止日期重复的MONTHLY规则")
+	}
+	sort.Slice(rule.ByDay, func(i, j int) bool {
+		return isoWeekday(rule.ByDay[i]) < isoWeekday(rule.ByDay[j])
+	})
+
+	return rule, nil
+}
+
+// String 返回规范化的规则字符串
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			codes[i] = weekdayCode(day)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.ByMonthDay != 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.ByMonthDay))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format(untilLayout))
+	}
+	if r.FromCompletion {
+		parts = append(parts, "X-FROM=COMPLETION")
+	}
+	return strings.Join(parts, ";")
+}
+
+// ICal 返回从第occurrence次（从1开始）起的RFC 5545 RRULE，用于导出到日历
+// UNTIL按loc中当天结束的时间转换为UTC；按完成时间起算的规则无法预知以后的时间，剩余次数不足两次时也没有重复，均返回false
+// 日期大于28日的MONTHLY规则转换为取28日到该日中当月存在的最后一天，与Next在短月份取最后一天一致
+func (r *Rule) ICal(occurrence int, loc *time.Location) (string, bool) {
+	if r.FromCompletion {
+		return "", false
+	}
+	rule := *r
+	if rule.Count > 0 {
+		rule.Count -= occurrence - 1
+		if rule.Count < 2 {
+			return "", false
+		}
+	}
+	rule.Until = nil
+	value := rule.String()
+	if rule.Freq == Monthly && rule.ByMonthDay > 28 {
+		days := make([]string, 0, rule.ByMonthDay-27)
+		for day := 28; day <= rule.ByMonthDay; day++ {
+			days = append(days, strconv.Itoa(day))
+		}
+		value = strings.Replace(value, "BYMONTHDAY="+strconv.Itoa(rule.ByMonthDay),
+			"BYMONTHDAY="+strings.Join(days, ",")+";BYSETPOS=-1", 1)
+	}
+	if r.Until != nil {
+		value += ";UNTIL=" + endOfDay(*r.Until, loc).UTC().Format("20060102T150405Z")
+	}
+	return value, true
+}
+
+// Next 计算下一次的截止时间
+// due 为本次的截止时间（可为空），completedAt 为本次完成的时间，occurrence 为本次是第几次（从1开始）
+// 规则已结束时返回 false
+func (r *Rule) Next(due *time.Time, completedAt time.Time, occurrence int) (time.Time, bool) {
+	if r.Count > 0 && occurrence >= r.Count {
+		return time.Time{}, false
+	}
+
+	// 按完成时间起算，或者任务没有截止日期时以完成时间为基准
+	base := completedAt
+	if due != nil && !r.FromCompletion {
+		base = *due
+	}
+
+	var next time.Time
+	switch r.Freq {
+	case Daily:
+		next = base.AddDate(0, 0, r.Interval)
+	case Weekly:
+		next = r.nextWeekly(base)
+	case Monthly:
+		next = r.nextMonthly(base, due)
+	}
+
+	if r.Until != nil && next.After(endOfDay(*r.Until, next.Location())) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// nextWeekly 计算WEEKLY规则的下一次时间
+func (r *Rule) nextWeekly(base time.Time) time.Time {
+	if len(r.ByDay) == 0 {
+		return base.AddDate(0, 0, 7*r.Interval)
+	}
+
+	// 在当前周剩余的天中查找，找不到则跳到间隔后的那一周
+	start := weekStart(base)
+	for d := base.AddDate(0, 0, 1); ; d = d.AddDate(0, 0, 1) {
+		weeks := daysBetween(start, weekStart(d)) / 7
+		if weeks%r.Interval == 0 && containsWeekday(r.ByDay, d.Weekday()) {
+			return d
+		}
+	}
+}
+
+// nextMonthly 计算MONTHLY规则的下一次时间，日期超出目标月份的天数时取该月最后一天
+func (r *Rule) nextMonthly(base time.Time, due *time.Time) time.Time {
+	day := r.ByMonthDay
+	if day == 0 {
+		day = base.Day()
+		if due != nil && !r.FromCompletion {
+			day = due.Day()
+		}
+	}
+
+	// 先定位到目标月份的1号，避免AddDate在月末溢出到下个月
+	first := time.Date(base.Year(), base.Month(), 1, base.Hour(), base.Minute(), base.Second(), 0, base.Location()).AddDate(0, r.Interval, 0)
+	last := first.AddDate(0, 1, -1).Day()
+	if day == -1 || day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// weekStart 返回所在周周一的零点
+func weekStart(t time.Time) time.Time {
+	d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return d.AddDate(0, 0, -(isoWeekday(d.Weekday()) - 1))
+}
+
+// daysBetween 计算两个日期相差的天数，按日历日计算，不受夏令时影响
+func daysBetween(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// endOfDay 返回日期当天的最后时刻
+func endOfDay(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 0, loc)
+}
+
+// isoWeekday 周一为1，周日为7
+func isoWeekday(day time.Weekday) int {
+	if day == time.Sunday {
+		return 7
+	}
+	return int(day)
+}
+
+// weekdayCode 返回星期的两字母缩写
+func weekdayCode(day time.Weekday) string {
+	for code, d := range weekdayCodes {
+		if d == day {
+			return code
+		}
+	}
+	return ""
+}
+
+// containsWeekday 判断星期是否在列表中
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+// date 返回UTC中某天09:00的时间
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 9, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string // 规范化后的规则，为空表示应解析失败
+		comment string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY", "最简单的规则"},
+		{"RRULE:freq=daily;interval=1", "FREQ=DAILY", "去掉前缀，INTERVAL=1省略"},
+		{"FREQ=WEEKLY;BYDAY=FR,MO,WE", "FREQ=WEEKLY;BYDAY=MO,WE,FR", "BYDAY按周一到周日排序"},
+		{"FREQ=WEEKLY;BYDAY=SU,MO", "FREQ=WEEKLY;BYDAY=MO,SU", "周日排在最后"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3", "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3", ""},
+		{"FREQ=DAILY;UNTIL=20261231T235959Z", "FREQ=DAILY;UNTIL=20261231", "UNTIL只保留日期"},
+		{"FREQ=DAILY;INTERVAL=3;X-FROM=COMPLETION", "FREQ=DAILY;INTERVAL=3;X-FROM=COMPLETION", ""},
+		{"", "", "空规则"},
+		{"INTERVAL=2", "", "缺少FREQ"},
+		{"FREQ=YEARLY", "", "不支持的频率"},
+		{"FREQ=DAILY;INTERVAL=0", "", "INTERVAL必须为正"},
+		{"FREQ=DAILY;BYDAY=MO", "", "BYDAY只能用于WEEKLY"},
+		{"FREQ=WEEKLY;BYDAY=XX", "", "无效的星期"},
+		{"FREQ=WEEKLY;BYMONTHDAY=1", "", "BYMONTHDAY只能用于MONTHLY"},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", "", "BYMONTHDAY超出范围"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-2", "", "只支持-1"},
+		{"FREQ=DAILY;COUNT=3;UNTIL=20261231", "", "COUNT和UNTIL不能同时使用"},
+		{"FREQ=WEEKLY;BYDAY=MO;X-FROM=COMPLETION", "", "按完成时间起算不能指定BYDAY"},
+		{"FREQ=DAILY;X-FROM=DUE", "", "无效的X-FROM"},
+		{"FREQ=DAILY;BYHOUR=9", "", "不支持的参数"},
+		{"FREQ", "", "缺少值"},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.value)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("Parse(%q) 应失败（%s），实际为 %s", tt.value, tt.comment, rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) 失败: %v", tt.value, err)
+			continue
+		}
+		if got := rule.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, 期望 %q（%s）", tt.value, got, tt.want, tt.comment)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	completedAt := date(2026, time.March, 10)
+	tests := []struct {
+		name       string
+		rule       string
+		due        *time.Time
+		occurrence int
+		want       time.Time // 零值表示规则已结束
+	}{
+		{"每天", "FREQ=DAILY", ptr(date(2026, time.October, 18)), 1, date(2026, time.October, 19)},
+		{"每3天", "FREQ=DAILY;INTERVAL=3", ptr(date(2026, time.October, 30)), 1, date(2026, time.November, 2)},
+		{"没有截止日期时从完成时间起算", "FREQ=DAILY", nil, 1, date(2026, time.March, 11)},
+		{"按完成时间起算", "FREQ=DAILY;INTERVAL=2;X-FROM=COMPLETION", ptr(date(2026, time.March, 1)), 1, date(2026, time.March, 12)},
+
+		{"每周", "FREQ=WEEKLY", ptr(date(2026, time.October, 16)), 1, date(2026, time.October, 23)},
+		{"每两周", "FREQ=WEEKLY;INTERVAL=2", ptr(date(2026, time.October, 16)), 1, date(2026, time.October, 30)},
+		{"BYDAY同一周内的下一天", "FREQ=WEEKLY;BYDAY=MO,WE,FR", ptr(date(2026, time.October, 19)), 1, date(2026, time.October, 21)},
+		{"BYDAY跨到下一周", "FREQ=WEEKLY;BYDAY=MO,WE,FR", ptr(date(2026, time.October, 23)), 1, date(2026, time.October, 26)},
+		{"BYDAY周日是一周的最后一天", "FREQ=WEEKLY;BYDAY=SA,SU", ptr(date(2026, time.October, 17)), 1, date(2026, time.October, 18)},
+		{"INTERVAL=2时同一周内继续", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", ptr(date(2026, time.October, 19)), 1, date(2026, time.October, 23)},
+		{"INTERVAL=2时跳过一周", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", ptr(date(2026, time.October, 23)), 1, date(2026, time.November, 2)},
+		{"截止日期不在BYDAY中", "FREQ=WEEKLY;BYDAY=TH", ptr(date(2026, time.October, 19)), 1, date(2026, time.October, 22)},
+
+		{"每月同一天", "FREQ=MONTHLY", ptr(date(2026, time.October, 18)), 1, date(2026, time.November, 18)},
+		{"每月指定日期", "FREQ=MONTHLY;BYMONTHDAY=5", ptr(date(2026, time.October, 18)), 1, date(2026, time.November, 5)},
+		{"每3个月", "FREQ=MONTHLY;INTERVAL=3", ptr(date(2026, time.November, 15)), 1, date(2027, time.February, 15)},
+		{"每月最后一天", "FREQ=MONTHLY;BYMONTHDAY=-1", ptr(date(2026, time.January, 31)), 1, date(2026, time.February, 28)},
+		{"每月最后一天到闰年2月", "FREQ=MONTHLY;BYMONTHDAY=-1", ptr(date(2028, time.January, 31)), 1, date(2028, time.February, 29)},
+		{"31日在2月取最后一天", "FREQ=MONTHLY;BYMONTHDAY=31", ptr(date(2026, time.January, 31)), 1, date(2026, time.February, 28)},
+		{"31日在闰年2月取29日", "FREQ=MONTHLY;BYMONTHDAY=31", ptr(date(2028, time.January, 31)), 1, date(2028, time.February, 29)},
+		{"31日在4月取30日", "FREQ=MONTHLY;BYMONTHDAY=31", ptr(date(2026, time.March, 31)), 1, date(2026, time.April, 30)},
+		{"31日取最后一天后回到31日", "FREQ=MONTHLY;BYMONTHDAY=31", ptr(date(2026, time.February, 28)), 2, date(2026, time.March, 31)},
+		{"30日在2月取最后一天", "FREQ=MONTHLY;BYMONTHDAY=30", ptr(date(2026, time.January, 30)), 1, date(2026, time.February, 28)},
+		{"未指定日期时沿用截止日期的日", "FREQ=MONTHLY", ptr(date(2026, time.January, 31)), 1, date(2026, time.February, 28)},
+		{"跨年", "FREQ=MONTHLY;BYMONTHDAY=31", ptr(date(2026, time.December, 31)), 1, date(2027, time.January, 31)},
+
+		{"COUNT未用完", "FREQ=DAILY;COUNT=3", ptr(date(2026, time.October, 18)), 2, date(2026, time.October, 19)},
+		{"COUNT已用完", "FREQ=DAILY;COUNT=3", ptr(date(2026, time.October, 18)), 3, time.Time{}},
+		{"UNTIL当天仍然重复", "FREQ=DAILY;UNTIL=20261019", ptr(date(2026, time.October, 18)), 1, date(2026, time.October, 19)},
+		{"超过UNTIL后结束", "FREQ=DAILY;UNTIL=20261019", ptr(date(2026, time.October, 19)), 2, time.Time{}},
+		{"WEEKLY超过UNTIL后结束", "FREQ=WEEKLY;BYDAY=MO;UNTIL=20261025", ptr(date(2026, time.October, 19)), 1, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q) 失败: %v", tt.rule, err)
+			}
+			got, ok := rule.Next(tt.due, completedAt, tt.occurrence)
+			if tt.want.IsZero() {
+				if ok {
+					t.Errorf("规则应已结束，实际下一次为 %v", got)
+				}
+				return
+			}
+			if !ok || !got.Equal(tt.want) {
+				t.Errorf("Next = %v, %v, 期望 %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextKeepsLocalTimeAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("没有时区数据: %v", err)
+	}
+	// 2026-03-29 开始夏令时，按日历日计算，下一次仍为当地09:00
+	due := time.Date(2026, time.March, 28, 9, 0, 0, 0, loc)
+	rule, _ := Parse("FREQ=DAILY")
+	got, _ := rule.Next(&due, due, 1)
+	if want := time.Date(2026, time.March, 29, 9, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("Next = %v, 期望 %v", got, want)
+	}
+}
+
+func TestICal(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	tests := []struct {
+		name       string
+		rule       string
+		occurrence int
+		want       string // 为空表示不导出RRULE
+	}{
+		{"普通规则原样导出", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", 1, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR"},
+		{"COUNT为剩余次数", "FREQ=DAILY;COUNT=5", 3, "FREQ=DAILY;COUNT=3"},
+		{"只剩一次时不导出", "FREQ=DAILY;COUNT=5", 5, ""},
+		{"UNTIL为当地当天结束的UTC时间", "FREQ=DAILY;UNTIL=20261231", 1, "FREQ=DAILY;UNTIL=20261231T155959Z"},
+		{"按完成时间起算时不导出", "FREQ=DAILY;X-FROM=COMPLETION", 1, ""},
+		{"每月最后一天", "FREQ=MONTHLY;BYMONTHDAY=-1", 1, "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{"28日及以前的日期原样导出", "FREQ=MONTHLY;BYMONTHDAY=28", 1, "FREQ=MONTHLY;BYMONTHDAY=28"},
+		{"31日在短月份取最后一天", "FREQ=MONTHLY;BYMONTHDAY=31", 1, "FREQ=MONTHLY;BYMONTHDAY=28,29,30,31;BYSETPOS=-1"},
+		{"30日在2月取最后一天", "FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=30;COUNT=4", 2, "FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=28,29,30;BYSETPOS=-1;COUNT=3"},
+		{"29日在平年2月取28日", "FREQ=MONTHLY;BYMONTHDAY=29;UNTIL=20261231", 1, "FREQ=MONTHLY;BYMONTHDAY=28,29;BYSETPOS=-1;UNTIL=20261231T155959Z"},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("Parse(%q) 失败: %v", tt.rule, err)
+		}
+		got, ok := rule.ICal(tt.occurrence, shanghai)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("%s: 不应导出RRULE，实际为 %q", tt.name, got)
+			}
+			continue
+		}
+		if !ok || got != tt.want {
+			t.Errorf("%s: ICal = %q, %v, 期望 %q", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
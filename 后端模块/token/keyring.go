@@ -0,0 +1,217 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"taskmanager/config"
+)
+
+// Key 一把带kid的签名/验证密钥
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{} // 仅当前签名密钥持有
+	verifyKey interface{}
+}
+
+// Keyring 密钥环
+// 使用一把当前密钥签名，使用所有已登记的密钥验证，从而支持密钥轮换
+type Keyring struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+// NewKeyring 根据JWT配置构建密钥环
+func NewKeyring(cfg config.JWTConfig) (*Keyring, error) {
+	if cfg.KeyID == "" {
+		return nil, errors.New("JWT_KID不能为空")
+	}
+
+	active, err := loadSigningKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ring := &Keyring{
+		active: active,
+		keys:   map[string]*Key{active.ID: active},
+	}
+
+	// 登记额外的验证密钥（轮换中的旧密钥或即将启用的新密钥）
+	for _, entry := range cfg.VerifyKeys {
+		key, err := parseVerifyKey(entry)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := ring.keys[key.ID]; exists {
+			return nil, fmt.Errorf("重复的JWT密钥ID: %s", key.ID)
+		}
+		ring.keys[key.ID] = key
+	}
+
+	return ring, nil
+}
+
+// ActiveKeyID 返回当前签名密钥的kid
+func (r *Keyring) ActiveKeyID() string {
+	return r.active.ID
+}
+
+// Sign 使用当前密钥签名，并在头部写入kid
+func (r *Keyring) Sign(claims jwt.Claims) (string, error) {
+	t := jwt.NewWithClaims(r.active.Method, claims)
+	t.Header["kid"] = r.active.ID
+	return t.SignedString(r.active.signKey)
+}
+
+// keyFunc 根据令牌头部的kid查找验证密钥，并拒绝与密钥不符的算法
+func (r *Keyring) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("令牌缺少kid")
+	}
+
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("未知的密钥ID: %s", kid)
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("密钥 %s 不接受算法 %s", kid, t.Method.Alg())
+	}
+	return key.verifyKey, nil
+}
+
+// loadSigningKey 加载当前签名密钥
+func loadSigningKey(cfg config.JWTConfig) (*Key, error) {
+	method, err := signingMethod(cfg.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: cfg.KeyID, Method: method}
+	if method == jwt.SigningMethodHS256 {
+		if cfg.Secret == "" {
+			return nil, errors.New("HS256需要配置JWT_KEY")
+		}
+		key.signKey = []byte(cfg.Secret)
+		key.verifyKey = key.signKey
+		return key, nil
+	}
+
+	if cfg.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("%s需要配置JWT_PRIVATE_KEY_FILE", cfg.Algorithm)
+	}
+	data, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取JWT私钥失败: %v", err)
+	}
+	privateKey, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if method != jwt.SigningMethodRS256 {
+			return nil, errors.New("RSA私钥只能用于RS256")
+		}
+		key.signKey, key.verifyKey = k, &k.PublicKey
+	case ed25519.PrivateKey:
+		if method != SigningMethodEd25519 {
+			return nil, errors.New("Ed25519私钥只能用于EdDSA")
+		}
+		key.signKey, key.verifyKey = k, k.Public()
+	default:
+		return nil, errors.New("不支持的JWT私钥类型")
+	}
+	return key, nil
+}
+
+// parseVerifyKey 解析 kid:算法:密钥 格式的验证密钥
+// HS256时密钥为共享密钥本身，RS256/EdDSA时为公钥PEM文件路径
+func parseVerifyKey(entry string) (*Key, error) {
+	parts := strings.SplitN(entry, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("无效的JWT验证密钥配置: %s", entry)
+	}
+
+	method, err := signingMethod(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: parts[0], Method: method}
+	if method == jwt.SigningMethodHS256 {
+		key.verifyKey = []byte(parts[2])
+		return key, nil
+	}
+
+	data, err := os.ReadFile(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("读取JWT公钥 %s 失败: %v", key.ID, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("JWT公钥 %s 不是有效的PEM", key.ID)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析JWT公钥 %s 失败: %v", key.ID, err)
+	}
+
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		if method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("JWT公钥 %s 与算法不匹配", key.ID)
+		}
+		key.verifyKey = k
+	case ed25519.PublicKey:
+		if method != SigningMethodEd25519 {
+			return nil, fmt.Errorf("JWT公钥 %s 与算法不匹配", key.ID)
+		}
+		key.verifyKey = k
+	default:
+		return nil, fmt.Errorf("JWT公钥 %s 类型不受支持", key.ID)
+	}
+	return key, nil
+}
+
+// parsePrivateKey 解析PKCS#1或PKCS#8格式的私钥
+func parsePrivateKey(data []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("JWT私钥不是有效的PEM")
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析JWT私钥失败: %v", err)
+	}
+	return k, nil
+}
+
+// signingMethod 将配置中的算法名映射为签名方法
+func signingMethod(alg string) (jwt.SigningMethod, error) {
+	switch strings.ToUpper(alg) {
+	case "HS256":
+		return jwt.SigningMethodHS256, nil
+	case "RS256":
+		return jwt.SigningMethodRS256, nil
+	case "EDDSA":
+		return SigningMethodEd25519, nil
+	default:
+		return nil, fmt.Errorf("不支持的JWT算法: %s", alg)
+	}
+}
@@ -0,0 +1,73 @@
+package token
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+
+	"taskmanager/config"
+)
+
+// Claims 访问令牌的Claims结构
+type Claims struct {
+	UserID uint `json:"userId"`
+	jwt.StandardClaims
+}
+
+// 全局密钥环
+var keyring *Keyring
+
+// 访问令牌有效期
+var accessTTL time.Duration
+
+// Init 根据应用配置初始化令牌签发与验证
+func Init(cfg config.JWTConfig) {
+	ring, err := NewKeyring(cfg)
+	if err != nil {
+		log.Fatalf("初始化JWT密钥失败: %v", err)
+	}
+
+	keyring = ring
+	accessTTL = cfg.AccessTTL
+	log.Printf("JWT密钥初始化成功，算法: %s, kid: %s, 验证密钥数: %d", ring.active.Method.Alg(), ring.ActiveKeyID(), len(ring.keys))
+}
+
+// IssueAccessToken 为用户签发访问令牌，返回令牌字符串及其Claims
+func IssueAccessToken(userID uint) (string, *Claims, error) {
+	now := time.Now()
+	claims := &Claims{
+		UserID: userID,
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.New().String(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(accessTTL).Unix(),
+		},
+	}
+
+	signed, err := keyring.Sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// Parse 验证访问令牌的签名和有效期，返回其中的Claims
+func Parse(tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	t, err := jwt.ParseWithClaims(tokenStr, claims, keyring.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	if !t.Valid {
+		return nil, errors.New("无效的令牌")
+	}
+	return claims, nil
+}
+
+// AccessTTL 返回访问令牌有效期
+func AccessTTL() time.Duration {
+	return accessTTL
+}
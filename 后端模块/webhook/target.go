@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress 目标地址是本机、链路本地或内网地址
+var ErrPrivateAddress = errors.New("不允许向本机或内网地址发送Webhook")
+
+// 除标准库能识别的私有地址外需要拒绝的网段
+var blockedNets = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),     // 本网络，Linux上连接时会落到本机
+	mustParseCIDR("100.64.0.0/10"), // 运营商级NAT，部分云平台的元数据服务在此网段
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// IsPrivateIP 判断是否为不应由Webhook访问的地址：
+// 回环、链路本地（含云平台元数据服务）、内网、未指定和组播地址
+func IsPrivateIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsPrivate() || ip.IsUnspecified() {
+		return true
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckHost 解析主机名并检查全部地址，有一个是内网地址时返回ErrPrivateAddress
+// 只用于保存Webhook时尽早提示；DNS结果可能变化，发送时还会在连接前再次检查
+func CheckHost(host string, allowPrivate bool) error {
+	if allowPrivate {
+		return nil
+	}
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		if ips, err = net.LookupIP(host); err != nil {
+			return fmt.Errorf("无法解析主机名 %s", host)
+		}
+	}
+	for _, ip := range ips {
+		if IsPrivateIP(ip) {
+			return ErrPrivateAddress
+		}
+	}
+	return nil
+}
+
+// NewClient 创建发送Webhook的HTTP客户端
+// 不跟随重定向，3xx按失败处理；allowPrivate为false时在DNS解析之后、建立连接之前拒绝内网地址，
+// 同时不使用环境变量中的代理，避免绕过检查
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || IsPrivateIP(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		}
+		transport.Proxy = nil
+	}
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
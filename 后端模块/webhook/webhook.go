@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 投递请求携带的请求头
+const (
+	HeaderEvent     = "X-Webhook-Event"     // 事件类型
+	HeaderDelivery  = "X-Webhook-Delivery"  // 投递ID，重试时不变，接收方可用于去重
+	HeaderTimestamp = "X-Webhook-Timestamp" // 发送时的Unix时间戳（秒）
+	HeaderSignature = "X-Webhook-Signature" // 签名，形如 sha256=<十六进制>
+)
+
+// 签名的前缀，标明签名算法
+const signaturePrefix = "sha256="
+
+// Delivery 一次投递的内容
+type Delivery struct {
+	ID        string    // 投递ID
+	Event     string    // 事件类型，如 task.updated
+	Payload   []byte    // JSON请求体
+	Timestamp time.Time // 发送时间，参与签名
+}
+
+// Sign 计算签名：以密钥对 "<时间戳>.<请求体>" 做HMAC-SHA256
+// 时间戳参与签名，接收方可以拒绝时间相差过大的请求以防重放
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验请求的签名，供接收方使用
+func Verify(secret, timestamp string, payload []byte, signature string) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, ts, payload)), []byte(signature))
+}
+
+// Sender 发送投递请求
+type Sender struct {
+	Client *http.Client // 为空时使用NewClient创建的10秒超时、拒绝内网地址的客户端
+}
+
+// Send 将投递POST到url，返回响应状态码；未收到响应时状态码为0
+// 响应状态码不是2xx时返回错误，调用方负责重试
+func (s *Sender) Send(url, secret string, d Delivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	ts := d.Timestamp.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TaskManager-Webhook/1.0")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, d.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, ts, d.Payload))
+
+	client := s.Client
+	if client == nil {
+		client = NewClient(10*time.Second, false)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// 读完响应体以便复用连接，只读取有限的长度
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook返回状态码 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
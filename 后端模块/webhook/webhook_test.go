@@ -0,0 +1,218 @@
+package webhook
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	payload := []byte(`{"event":"task.updated"}`)
+	ts := int64(1672574400)
+	signature := Sign("0123456789abcdef", ts, payload)
+
+	if !strings.HasPrefix(signature, "sha256=") || len(signature) != len("sha256=")+64 {
+		t.Fatalf("签名格式错误: %s", signature)
+	}
+	if Sign("0123456789abcdef", ts, payload) != signature {
+		t.Fatal("相同输入的签名应相同")
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		payload   []byte
+		signature string
+		want      bool
+	}{
+		{"正确的签名", "0123456789abcdef", "1672574400", payload, signature, true},
+		{"密钥不同", "fedcba9876543210", "1672574400", payload, signature, false},
+		{"时间戳被修改", "0123456789abcdef", "1672574401", payload, signature, false},
+		{"请求体被修改", "0123456789abcdef", "1672574400", []byte(`{"event":"task.deleted"}`), signature, false},
+		{"时间戳无效", "0123456789abcdef", "abc", payload, signature, false},
+		{"缺少前缀", "0123456789abcdef", "1672574400", payload, strings.TrimPrefix(signature, "sha256="), false},
+	}
+	for _, tt := range tests {
+		if got := Verify(tt.secret, tt.timestamp, tt.payload, tt.signature); got != tt.want {
+			t.Errorf("%s: Verify = %v, 期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	d := Delivery{
+		ID:        "42",
+		Event:     "task.updated",
+		Payload:   []byte(`{"event":"task.updated","data":{"taskId":5}}`),
+		Timestamp: time.Unix(1672574400, 0),
+	}
+	sender := &Sender{Client: NewClient(time.Second, true)}
+	status, err := sender.Send(server.URL, "0123456789abcdef", d)
+	if err != nil || status != http.StatusAccepted {
+		t.Fatalf("Send = %d, %v", status, err)
+	}
+
+	if string(body) != string(d.Payload) {
+		t.Errorf("请求体 = %s", body)
+	}
+	for name, want := range map[string]string{
+		"Content-Type":  "application/json",
+		HeaderEvent:     "task.updated",
+		HeaderDelivery:  "42",
+		HeaderTimestamp: "1672574400",
+	} {
+		if got := header.Get(name); got != want {
+			t.Errorf("%s = %q, 期望 %q", name, got, want)
+		}
+	}
+	if !Verify("0123456789abcdef", header.Get(HeaderTimestamp), body, header.Get(HeaderSignature)) {
+		t.Errorf("签名校验失败: %s", header.Get(HeaderSignature))
+	}
+}
+
+func TestSendFailure(t *testing.T) {
+	tests := []struct {
+		status int
+	}{
+		{http.StatusFound},
+		{http.StatusNotFound},
+		{http.StatusInternalServerError},
+		{http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// 3xx不跟随重定向
+			if tt.status == http.StatusFound {
+				w.Header().Set("Location", "/elsewhere")
+			}
+			w.WriteHeader(tt.status)
+		}))
+		sender := &Sender{Client: NewClient(time.Second, true)}
+		status, err := sender.Send(server.URL, "0123456789abcdef", Delivery{ID: "1", Event: "task.created", Timestamp: time.Now()})
+		server.Close()
+		if err == nil {
+			t.Errorf("状态码 %d 应返回错误以便重试", tt.status)
+		}
+		if status != tt.status {
+			t.Errorf("返回的状态码 = %d, 期望 %d", status, tt.status)
+		}
+	}
+
+	// 连接失败时没有状态码
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+	sender := &Sender{Client: NewClient(time.Second, true)}
+	if status, err := sender.Send(url, "0123456789abcdef", Delivery{ID: "1", Timestamp: time.Now()}); err == nil || status != 0 {
+		t.Errorf("连接失败: Send = %d, %v", status, err)
+	}
+}
+
+func TestSendRejectsPrivateAddress(t *testing.T) {
+	hit := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer server.Close()
+
+	// 默认客户端和不允许内网地址的客户端都应拒绝，主机名解析到回环地址时同样拒绝
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+	for _, sender := range []*Sender{{}, {Client: NewClient(time.Second, false)}} {
+		for _, url := range []string{server.URL, "http://localhost:" + port} {
+			status, err := sender.Send(url, "0123456789abcdef", Delivery{ID: "1", Timestamp: time.Now()})
+			if !errors.Is(err, ErrPrivateAddress) || status != 0 {
+				t.Errorf("%s: Send = %d, %v, 期望 ErrPrivateAddress", url, status, err)
+			}
+		}
+	}
+	if hit {
+		t.Error("请求不应到达内网地址")
+	}
+}
+
+func TestIsPrivateIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"127.8.9.10", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"0.0.0.0", true},
+		{"::", true},
+		{"100.100.100.200", true},
+		{"224.0.0.1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:10.0.0.1", true},
+		{"8.8.8.8", false},
+		{"172.32.0.1", false},
+		{"100.128.0.1", false},
+		{"2001:4860:4860::8888", false},
+	}
+	for _, tt := range tests {
+		if got := IsPrivateIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("IsPrivateIP(%s) = %v, 期望 %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	tests := []struct {
+		host         string
+		allowPrivate bool
+		wantErr      error
+	}{
+		{"127.0.0.1", false, ErrPrivateAddress},
+		{"localhost", false, ErrPrivateAddress},
+		{"::1", false, ErrPrivateAddress},
+		{"169.254.169.254", false, ErrPrivateAddress},
+		{"8.8.8.8", false, nil},
+		{"127.0.0.1", true, nil},
+		{"localhost", true, nil},
+	}
+	for _, tt := range tests {
+		if err := CheckHost(tt.host, tt.allowPrivate); err != tt.wantErr {
+			t.Errorf("CheckHost(%s, %v) = %v, 期望 %v", tt.host, tt.allowPrivate, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNewClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	sender := &Sender{Client: NewClient(50*time.Millisecond, true)}
+	start := time.Now()
+	_, err := sender.Send(server.URL, "0123456789abcdef", Delivery{ID: "1", Timestamp: start})
+	if err == nil {
+		t.Fatal("超时应返回错误")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("超时未生效，耗时 %s", elapsed)
+	}
+}